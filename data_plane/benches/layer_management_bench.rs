@@ -3,6 +3,7 @@ use experiment_data_plane::catalog::{ExperimentCatalog, ExperimentDef, VariantDe
 use experiment_data_plane::layer::{BucketRange, Layer, LayerManager};
 use rand::Rng;
 use serde_json::json;
+use std::collections::HashMap;
 use tempfile::TempDir;
 
 /// Create random test catalog
@@ -21,6 +22,8 @@ fn create_random_catalog(num_experiments: usize) -> (TempDir, ExperimentCatalog)
                 vid: (1000 + i * 10) as i64,
                 params: json!({"feature": i}),
             }],
+            overrides: HashMap::new(),
+            prerequisites: vec![],
         };
 
         std::fs::write(
@@ -49,6 +49,8 @@ fn create_catalog_with_random_params(
                 vid: (1000 + i * 10) as i64,
                 params,
             }],
+            overrides: HashMap::new(),
+            prerequisites: vec![],
         };
 
         std::fs::write(
@@ -299,6 +301,8 @@ fn bench_merge_conflicts(c: &mut Criterion) {
                     vid: (1000 + i * 10) as i64,
                     params,
                 }],
+                overrides: HashMap::new(),
+                prerequisites: vec![],
             };
 
             std::fs::write(
@@ -11,6 +11,7 @@ fn create_nested_rule(depth: usize, seed: usize) -> Node {
             field: format!("field_{}", seed % 20),
             op: Op::Eq,
             values: vec![json!(seed % 100)],
+            regex: Default::default(),
         };
     }
 
@@ -74,6 +75,7 @@ fn bench_simple_rules(c: &mut Criterion) {
                 field: "country".to_string(),
                 op: Op::Eq,
                 values: vec![json!("US")],
+                regex: Default::default(),
             },
         ),
         (
@@ -82,6 +84,7 @@ fn bench_simple_rules(c: &mut Criterion) {
                 field: "country".to_string(),
                 op: Op::In,
                 values: vec![json!("US"), json!("CA"), json!("UK")],
+                regex: Default::default(),
             },
         ),
         (
@@ -90,6 +93,7 @@ fn bench_simple_rules(c: &mut Criterion) {
                 field: "age".to_string(),
                 op: Op::Gte,
                 values: vec![json!(18)],
+                regex: Default::default(),
             },
         ),
     ];
@@ -152,6 +156,7 @@ fn bench_rule_width(c: &mut Criterion) {
                 field: format!("field_{}", i),
                 op: Op::Eq,
                 values: vec![json!(i * 10)],
+                regex: Default::default(),
             })
             .collect();
 
@@ -200,11 +205,13 @@ fn bench_complex_patterns(c: &mut Criterion) {
                         field: "country".to_string(),
                         op: Op::Eq,
                         values: vec![json!("US")],
+                        regex: Default::default(),
                     },
                     Node::Field {
                         field: "country".to_string(),
                         op: Op::Eq,
                         values: vec![json!("CA")],
+                        regex: Default::default(),
                     },
                 ],
             },
@@ -212,6 +219,7 @@ fn bench_complex_patterns(c: &mut Criterion) {
                 field: "age".to_string(),
                 op: Op::Gte,
                 values: vec![json!(18)],
+                regex: Default::default(),
             },
         ],
     };
@@ -227,11 +235,13 @@ fn bench_complex_patterns(c: &mut Criterion) {
                                 field: "country".to_string(),
                                 op: Op::In,
                                 values: vec![json!("US"), json!("CA"), json!("UK")],
+                                regex: Default::default(),
                             },
                             Node::Field {
                                 field: "age".to_string(),
                                 op: Op::Gte,
                                 values: vec![json!(18)],
+                                regex: Default::default(),
                             },
                         ],
                     },
@@ -239,6 +249,7 @@ fn bench_complex_patterns(c: &mut Criterion) {
                         field: "premium".to_string(),
                         op: Op::Eq,
                         values: vec![json!(true)],
+                        regex: Default::default(),
                     },
                 ],
             },
@@ -246,6 +257,7 @@ fn bench_complex_patterns(c: &mut Criterion) {
                 field: "score".to_string(),
                 op: Op::Gt,
                 values: vec![json!(70)],
+                regex: Default::default(),
             },
         ],
     };
@@ -1,8 +1,12 @@
 use crate::error::{ExperimentError, Result};
+use crate::segment::SegmentSet;
 use serde::{Deserialize, Serialize};
 use std::collections::HashMap;
 use std::path::{Path, PathBuf};
 
+/// Maximum number of forced assignments per experiment
+pub const MAX_OVERRIDES_PER_EXPERIMENT: usize = 10000;
+
 /// Experiment-level definition (strong cohesion)
 #[derive(Debug, Clone, Serialize, Deserialize)]
 pub struct ExperimentDef {
@@ -18,6 +22,24 @@ pub struct ExperimentDef {
 
     /// Variants under this experiment (only params differ, controlled variable)
     pub variants: Vec<VariantDef>,
+
+    /// Forced assignments: hash key value (e.g. user_id) → vid.
+    /// Used by QA/PMs to pin units to a variant; bypasses bucketing and the rule.
+    #[serde(default, skip_serializing_if = "HashMap::is_empty")]
+    pub overrides: HashMap<String, i64>,
+
+    /// Experiments the unit must already be in before this one applies.
+    /// Prerequisites belong to the same service and are resolved by
+    /// higher-priority layers earlier in the same request.
+    #[serde(default, skip_serializing_if = "Vec::is_empty")]
+    pub prerequisites: Vec<Prerequisite>,
+}
+
+/// Prerequisite: the unit must be in one of `vids` of experiment `eid`
+#[derive(Debug, Clone, Serialize, Deserialize, PartialEq)]
+pub struct Prerequisite {
+    pub eid: i64,
+    pub vids: Vec<i64>,
 }
 
 /// Variant definition within an experiment
@@ -39,6 +61,12 @@ pub struct ExperimentCatalog {
     /// vid → eid reverse index (for fast lookup during merge)
     vid_to_eid: HashMap<i64, i64>,
 
+    /// unit → forced vids (across all experiments), from `ExperimentDef::overrides`
+    overrides: HashMap<String, Vec<i64>>,
+
+    /// Segments referenced by `in_segment` in experiment rules
+    segments: SegmentSet,
+
     source_dir: PathBuf,
 }
 
@@ -49,12 +77,15 @@ impl ExperimentCatalog {
             return Ok(Self {
                 experiments: HashMap::new(),
                 vid_to_eid: HashMap::new(),
+                overrides: HashMap::new(),
+                segments: SegmentSet::default(),
                 source_dir: dir,
             });
         }
 
         let mut experiments: HashMap<i64, ExperimentDef> = HashMap::new();
         let mut vid_to_eid: HashMap<i64, i64> = HashMap::new();
+        let mut overrides: HashMap<String, Vec<i64>> = HashMap::new();
 
         for entry in std::fs::read_dir(&dir)? {
             let entry = entry?;
@@ -83,6 +114,13 @@ impl ExperimentCatalog {
 
             // Build reverse index: vid → eid
             for variant in &exp_def.variants {
+                // Params are merged key by key; anything but an object is a config fault
+                if !variant.params.is_object() {
+                    return Err(ExperimentError::InvalidParameter(format!(
+                        "Variant {} of eid {} params must be an object (file: {:?})",
+                        variant.vid, exp_def.eid, path
+                    )));
+                }
                 if let Some(existing_eid) = vid_to_eid.insert(variant.vid, exp_def.eid) {
                     return Err(ExperimentError::InvalidParameter(format!(
                         "Duplicate vid {} (belongs to eid {} and {})",
@@ -91,16 +129,59 @@ impl ExperimentCatalog {
                 }
             }
 
+            validate_overrides(&exp_def)?;
+            if let Some(rule) = &exp_def.rule {
+                rule.check_structure().map_err(|e| match e {
+                    ExperimentError::InvalidRule(msg) => ExperimentError::InvalidRule(format!(
+                        "Experiment {} (file: {:?}): {}",
+                        exp_def.eid, path, msg
+                    )),
+                    other => other,
+                })?;
+            }
+            for (unit, vid) in &exp_def.overrides {
+                overrides.entry(unit.clone()).or_insert_with(Vec::new).push(*vid);
+            }
+
             experiments.insert(exp_def.eid, exp_def);
         }
 
+        validate_prerequisites(&experiments)?;
+
+        // Deterministic pick when several experiments override the same unit
+        for vids in overrides.values_mut() {
+            vids.sort_unstable();
+        }
+
         Ok(Self {
             experiments,
             vid_to_eid,
+            overrides,
+            segments: SegmentSet::default(),
             source_dir: dir,
         })
     }
 
+    /// Attach segments, checking that every `in_segment` reference resolves
+    pub fn with_segments(mut self, segments: SegmentSet) -> Result<Self> {
+        let mut eids: Vec<&i64> = self.experiments.keys().collect();
+        eids.sort();
+        for eid in eids {
+            let Some(rule) = &self.experiments[eid].rule else {
+                continue;
+            };
+            if let Some(name) = rule.segment_refs().into_iter().find(|n| !segments.contains(n)) {
+                return Err(ExperimentError::InvalidRule(format!(
+                    "Experiment {} references unknown segment '{}'",
+                    eid, name
+                )));
+            }
+        }
+
+        self.segments = segments;
+        Ok(self)
+    }
+
     fn read_experiment_file(path: &Path) -> Result<ExperimentDef> {
         let content = std::fs::read_to_string(path)?;
 
@@ -131,6 +212,23 @@ impl ExperimentCatalog {
         Some((eid, exp.service.as_str(), exp.rule.as_ref(), &variant.params))
     }
 
+    /// Get forced vids for a unit (hash key value), across all experiments
+    #[inline]
+    pub fn get_overrides(&self, unit: &str) -> &[i64] {
+        self.overrides.get(unit).map(Vec::as_slice).unwrap_or(&[])
+    }
+
+    /// Iterate over all experiments (unordered)
+    pub fn experiments(&self) -> impl Iterator<Item = &ExperimentDef> {
+        self.experiments.values()
+    }
+
+    /// Segments available to `in_segment`
+    #[inline]
+    pub fn segments(&self) -> &SegmentSet {
+        &self.segments
+    }
+
     /// Get all services from catalog (for building inverted index)
     #[allow(dead_code)]
     pub fn get_all_services(&self) -> Vec<String> {
@@ -159,3 +257,175 @@ impl ExperimentCatalog {
         &self.source_dir
     }
 }
+
+/// Validate forced assignments: bounded size, and every vid belongs to the experiment
+fn validate_overrides(exp_def: &ExperimentDef) -> Result<()> {
+    if exp_def.overrides.len() > MAX_OVERRIDES_PER_EXPERIMENT {
+        return Err(ExperimentError::InvalidParameter(format!(
+            "Experiment {} has {} overrides, exceeds limit {}",
+            exp_def.eid,
+            exp_def.overrides.len(),
+            MAX_OVERRIDES_PER_EXPERIMENT
+        )));
+    }
+
+    for (unit, vid) in &exp_def.overrides {
+        if !exp_def.variants.iter().any(|v| v.vid == *vid) {
+            return Err(ExperimentError::InvalidParameter(format!(
+                "Override for unit '{}' in experiment {} references vid {} outside the experiment",
+                unit, exp_def.eid, vid
+            )));
+        }
+    }
+
+    Ok(())
+}
+
+/// Validate prerequisites: referenced experiments and vids exist in the same
+/// service, and the prerequisite graph has no cycles
+fn validate_prerequisites(experiments: &HashMap<i64, ExperimentDef>) -> Result<()> {
+    let mut eids: Vec<i64> = experiments.keys().copied().collect();
+    eids.sort_unstable();
+
+    for eid in &eids {
+        let exp_def = &experiments[eid];
+        for prereq in &exp_def.prerequisites {
+            let Some(target) = experiments.get(&prereq.eid) else {
+                return Err(ExperimentError::InvalidParameter(format!(
+                    "Experiment {} prerequisite references unknown eid {}",
+                    eid, prereq.eid
+                )));
+            };
+
+            if target.service != exp_def.service {
+                return Err(ExperimentError::InvalidParameter(format!(
+                    "Experiment {} prerequisite eid {} belongs to service '{}', expected '{}'",
+                    eid, prereq.eid, target.service, exp_def.service
+                )));
+            }
+
+            if prereq.vids.is_empty() {
+                return Err(ExperimentError::InvalidParameter(format!(
+                    "Experiment {} prerequisite eid {} must list at least one vid",
+                    eid, prereq.eid
+                )));
+            }
+
+            if let Some(vid) = prereq
+                .vids
+                .iter()
+                .find(|vid| !target.variants.iter().any(|v| v.vid == **vid))
+            {
+                return Err(ExperimentError::InvalidParameter(format!(
+                    "Experiment {} prerequisite references vid {} outside experiment {}",
+                    eid, vid, prereq.eid
+                )));
+            }
+        }
+    }
+
+    // Depth-first search; `false` while on the current path, `true` once done
+    let mut visited: HashMap<i64, bool> = HashMap::new();
+    let mut path = Vec::new();
+    for eid in eids {
+        visit_prerequisites(eid, experiments, &mut visited, &mut path)?;
+    }
+
+    Ok(())
+}
+
+fn visit_prerequisites(
+    eid: i64,
+    experiments: &HashMap<i64, ExperimentDef>,
+    visited: &mut HashMap<i64, bool>,
+    path: &mut Vec<i64>,
+) -> Result<()> {
+    match visited.get(&eid) {
+        Some(true) => return Ok(()),
+        Some(false) => {
+            let start = path.iter().position(|e| *e == eid).unwrap_or(0);
+            let cycle: Vec<String> = path[start..]
+                .iter()
+                .chain(std::iter::once(&eid))
+                .map(|e| e.to_string())
+                .collect();
+            return Err(ExperimentError::InvalidParameter(format!(
+                "Prerequisite cycle: {}",
+                cycle.join(" -> ")
+            )));
+        }
+        None => {}
+    }
+
+    visited.insert(eid, false);
+    path.push(eid);
+    for prereq in &experiments[&eid].prerequisites {
+        visit_prerequisites(prereq.eid, experiments, visited, path)?;
+    }
+    path.pop();
+    visited.insert(eid, true);
+
+    Ok(())
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use tempfile::TempDir;
+
+    #[test]
+    fn test_non_object_params_rejected() {
+        let temp_dir = TempDir::new().unwrap();
+        std::fs::write(
+            temp_dir.path().join("100.json"),
+            r#"{"eid": 100, "service": "svc", "variants": [{"vid": 1001, "params": [1, 2]}]}"#,
+        )
+        .unwrap();
+
+        let err = ExperimentCatalog::load_from_dir(temp_dir.path().to_path_buf()).unwrap_err();
+        assert!(err.to_string().contains("params must be an object"));
+    }
+
+    #[test]
+    fn test_prerequisites_validated() {
+        let write = |dir: &Path, eid: i64, service: &str, prerequisites: serde_json::Value| {
+            let exp = serde_json::json!({
+                "eid": eid,
+                "service": service,
+                "variants": [{"vid": eid * 10, "params": {}}, {"vid": eid * 10 + 1, "params": {}}],
+                "prerequisites": prerequisites,
+            });
+            std::fs::write(dir.join(format!("{}.json", eid)), exp.to_string()).unwrap();
+        };
+        let load = |dir: &Path| ExperimentCatalog::load_from_dir(dir.to_path_buf());
+
+        // 2 requires variant 10 of 1
+        let temp_dir = TempDir::new().unwrap();
+        write(temp_dir.path(), 1, "svc", serde_json::json!([]));
+        write(temp_dir.path(), 2, "svc", serde_json::json!([{"eid": 1, "vids": [10]}]));
+        let catalog = load(temp_dir.path()).unwrap();
+        assert_eq!(
+            catalog.get_experiment(2).unwrap().prerequisites,
+            vec![Prerequisite { eid: 1, vids: vec![10] }]
+        );
+
+        // vid outside the prerequisite experiment
+        write(temp_dir.path(), 2, "svc", serde_json::json!([{"eid": 1, "vids": [20]}]));
+        assert!(load(temp_dir.path()).is_err());
+
+        // unknown eid
+        write(temp_dir.path(), 2, "svc", serde_json::json!([{"eid": 9, "vids": [90]}]));
+        assert!(load(temp_dir.path()).is_err());
+
+        // other service
+        write(temp_dir.path(), 1, "other", serde_json::json!([]));
+        write(temp_dir.path(), 2, "svc", serde_json::json!([{"eid": 1, "vids": [10]}]));
+        assert!(load(temp_dir.path()).is_err());
+
+        // 1 -> 3 -> 2 -> 1
+        write(temp_dir.path(), 1, "svc", serde_json::json!([{"eid": 3, "vids": [30]}]));
+        write(temp_dir.path(), 3, "svc", serde_json::json!([{"eid": 2, "vids": [20]}]));
+        let err = load(temp_dir.path()).unwrap_err();
+        assert!(err.to_string().contains("Prerequisite cycle: 1 -> 3 -> 2 -> 1"));
+    }
+}
@@ -5,8 +5,12 @@ use std::path::PathBuf;
 pub struct Config {
     pub layers_dir: PathBuf,
     pub experiments_dir: PathBuf,
+    pub segments_dir: PathBuf,
     pub server_host: String,
     pub server_port: u16,
+    /// Listener for `/admin/*`, kept off the serving port; loopback by default
+    pub admin_host: String,
+    pub admin_port: u16,
     #[allow(dead_code)]
     pub metrics_port: u16,
 }
@@ -24,10 +28,17 @@ impl Config {
                 .unwrap_or_else(|_| "../configs/layers".to_string())
                 .into(),
             experiments_dir,
+            segments_dir: std::env::var("SEGMENTS_DIR")
+                .unwrap_or_else(|_| "../configs/segments".to_string())
+                .into(),
             server_host: std::env::var("SERVER_HOST").unwrap_or_else(|_| "0.0.0.0".to_string()),
             server_port: std::env::var("SERVER_PORT")
                 .unwrap_or_else(|_| "8080".to_string())
                 .parse()?,
+            admin_host: std::env::var("ADMIN_HOST").unwrap_or_else(|_| "127.0.0.1".to_string()),
+            admin_port: std::env::var("ADMIN_PORT")
+                .unwrap_or_else(|_| "9091".to_string())
+                .parse()?,
             metrics_port: std::env::var("METRICS_PORT")
                 .unwrap_or_else(|_| "9090".to_string())
                 .parse()?,
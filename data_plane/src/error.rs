@@ -43,4 +43,72 @@ pub enum ExperimentError {
     Yaml(#[from] serde_yaml::Error),
 }
 
+/// Error category, mapped uniformly to a response status by the server
+#[derive(Debug, Clone, Copy, PartialEq, Eq)]
+pub enum ErrorKind {
+    /// Referenced layer/group/bucket does not exist
+    NotFound,
+    /// Request conflicts with current state (e.g. nothing to roll back to)
+    Conflict,
+    /// Request or config content is invalid
+    Validation,
+    /// IO or (de)serialization failure on our side
+    Internal,
+}
+
+impl ErrorKind {
+    /// Stable machine-readable code returned alongside the message
+    pub fn code(self) -> &'static str {
+        match self {
+            ErrorKind::NotFound => "not_found",
+            ErrorKind::Conflict => "conflict",
+            ErrorKind::Validation => "validation",
+            ErrorKind::Internal => "internal",
+        }
+    }
+}
+
+impl ExperimentError {
+    pub fn kind(&self) -> ErrorKind {
+        match self {
+            ExperimentError::LayerNotFound(_)
+            | ExperimentError::BucketNotFound(_)
+            | ExperimentError::GroupNotFound(_) => ErrorKind::NotFound,
+            ExperimentError::InvalidVersion(_) => ErrorKind::Conflict,
+            ExperimentError::HashKeyNotFound(_)
+            | ExperimentError::ServiceMismatch { .. }
+            | ExperimentError::InvalidParameter(_)
+            | ExperimentError::InvalidRule(_)
+            | ExperimentError::RuleEvaluationFailed(_) => ErrorKind::Validation,
+            ExperimentError::Io(_) | ExperimentError::Json(_) | ExperimentError::Yaml(_) => {
+                ErrorKind::Internal
+            }
+        }
+    }
+}
+
 pub type Result<T> = std::result::Result<T, ExperimentError>;
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    #[test]
+    fn test_error_kind() {
+        assert_eq!(
+            ExperimentError::LayerNotFound("l".to_string()).kind(),
+            ErrorKind::NotFound
+        );
+        assert_eq!(
+            ExperimentError::InvalidVersion("v".to_string()).kind(),
+            ErrorKind::Conflict
+        );
+        assert_eq!(
+            ExperimentError::InvalidRule("r".to_string()).kind(),
+            ErrorKind::Validation
+        );
+        let io = std::io::Error::new(std::io::ErrorKind::Other, "disk");
+        assert_eq!(ExperimentError::from(io).kind(), ErrorKind::Internal);
+        assert_eq!(ErrorKind::NotFound.code(), "not_found");
+    }
+}
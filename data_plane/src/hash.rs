@@ -3,6 +3,14 @@ use crate::layer::BUCKET_SIZE;
 
 /// Hash a key with salt to a bucket index
 /// Salt ensures different layers produce different distributions for the same key
+///
+/// Algorithm (must stay identical across every data plane / SDK):
+/// 1. Concatenate the UTF-8 bytes of `key` and `salt`, no separator
+/// 2. Hash with XXH3-64 (seed 0)
+/// 3. bucket = hash % BUCKET_SIZE (10000)
+///
+/// The bucket is then looked up in the layer's half-open ranges (`Layer::get_vid`).
+/// `test_golden_vectors` pins reference outputs for other implementations.
 pub fn hash_to_bucket(key: &str, salt: &str) -> u32 {
     // Concatenate key and salt, then hash
     let combined = format!("{}{}", key, salt);
@@ -21,6 +29,24 @@ mod tests {
         assert!(bucket < BUCKET_SIZE);
     }
     
+    #[test]
+    fn test_golden_vectors() {
+        // (key, salt, xxh3_64(key ++ salt), bucket)
+        let vectors: [(&str, &str, u64, u32); 6] = [
+            ("user_123", "layer1_v1", 0x533440e912b58b40, 7984),
+            ("user_456", "experiment_v2", 0x55e0cfe66670cd6c, 7868),
+            ("user_789", "ranker_exp_2026", 0x4d147474681ce82c, 7452),
+            ("12345", "click_experiment_v1", 0x34aacf150a3913a3, 9779),
+            ("用户_1", "salt", 0x82287d5c79176f7c, 156),
+            ("", "", 0x2d06800538d394c2, 3138),
+        ];
+        
+        for (key, salt, hash, bucket) in vectors {
+            assert_eq!(xxh3_64(format!("{}{}", key, salt).as_bytes()), hash, "{} + {}", key, salt);
+            assert_eq!(hash_to_bucket(key, salt), bucket, "{} + {}", key, salt);
+        }
+    }
+    
     #[test]
     fn test_hash_determinism() {
         let key = "user_456";
@@ -0,0 +1,180 @@
+use parking_lot::RwLock;
+use serde::Serialize;
+use std::collections::BTreeMap;
+use std::time::{SystemTime, UNIX_EPOCH};
+
+/// Layer hot reload (degraded while any layer's last reload or removal failed)
+pub const LAYERS: &str = "layers";
+
+/// Layers directory watcher
+pub const WATCHER: &str = "watcher";
+
+#[derive(Debug, Clone, Copy, PartialEq, Eq, Serialize)]
+#[serde(rename_all = "snake_case")]
+pub enum HealthStatus {
+    /// Registered but not yet running
+    Starting,
+    Up,
+    /// Running, but some operations are failing (still serving last good state)
+    Degraded,
+    Down,
+}
+
+/// Health of a single subsystem
+#[derive(Debug, Clone, Serialize)]
+pub struct ComponentHealth {
+    pub status: HealthStatus,
+    /// Last error reported, kept after recovery for diagnosis
+    #[serde(skip_serializing_if = "Option::is_none")]
+    pub last_error: Option<String>,
+    /// Unix seconds when `last_error` was reported
+    #[serde(skip_serializing_if = "Option::is_none")]
+    pub last_error_at: Option<u64>,
+    /// Outstanding failures keyed by what failed (e.g. layer_id); the
+    /// component stays degraded until each one is cleared
+    #[serde(skip_serializing_if = "BTreeMap::is_empty")]
+    pub failing: BTreeMap<String, String>,
+}
+
+/// Per-component health, reported by each subsystem and aggregated by `/readyz`
+#[derive(Debug, Default)]
+pub struct HealthRegistry {
+    components: RwLock<BTreeMap<&'static str, ComponentHealth>>,
+}
+
+impl HealthRegistry {
+    pub fn new() -> Self {
+        Self::default()
+    }
+
+    /// Register a component in `Starting` state
+    pub fn register(&self, name: &'static str) {
+        self.components.write().insert(
+            name,
+            ComponentHealth {
+                status: HealthStatus::Starting,
+                last_error: None,
+                last_error_at: None,
+                failing: BTreeMap::new(),
+            },
+        );
+    }
+
+    /// Mark a component up, or degraded if it still has outstanding failures
+    pub fn set_up(&self, name: &'static str) {
+        self.update(name, |component| component.status = HealthStatus::Up);
+    }
+
+    /// Record a failure of `key` (e.g. a layer_id), marking the component degraded
+    pub fn record_failure(&self, name: &'static str, key: &str, error: impl ToString) {
+        let error = error.to_string();
+        self.update(name, |component| {
+            component.status = HealthStatus::Degraded;
+            component.failing.insert(key.to_string(), error.clone());
+            component.last_error = Some(error);
+            component.last_error_at = unix_now();
+        });
+    }
+
+    /// Clear the failure of `key`; the component is up again once none remain
+    pub fn clear_failure(&self, name: &'static str, key: &str) {
+        self.update(name, |component| {
+            component.failing.remove(key);
+            if component.status == HealthStatus::Degraded {
+                component.status = HealthStatus::Up;
+            }
+        });
+    }
+
+    /// Mark a component down, recording the error if there is one
+    pub fn set_down(&self, name: &'static str, error: Option<String>) {
+        self.update(name, |component| {
+            component.status = HealthStatus::Down;
+            if let Some(error) = error {
+                component.last_error = Some(error);
+                component.last_error_at = unix_now();
+            }
+        });
+    }
+
+    /// Apply `f` to a component (registering it if needed); an `Up` component
+    /// with outstanding failures is reported as `Degraded`
+    fn update(&self, name: &'static str, f: impl FnOnce(&mut ComponentHealth)) {
+        let mut components = self.components.write();
+        let component = components.entry(name).or_insert(ComponentHealth {
+            status: HealthStatus::Starting,
+            last_error: None,
+            last_error_at: None,
+            failing: BTreeMap::new(),
+        });
+
+        f(component);
+        if component.status == HealthStatus::Up && !component.failing.is_empty() {
+            component.status = HealthStatus::Degraded;
+        }
+    }
+
+    pub fn snapshot(&self) -> BTreeMap<&'static str, ComponentHealth> {
+        self.components.read().clone()
+    }
+
+    /// Ready when every component is up or degraded
+    pub fn is_ready(&self) -> bool {
+        self.components
+            .read()
+            .values()
+            .all(|c| matches!(c.status, HealthStatus::Up | HealthStatus::Degraded))
+    }
+}
+
+fn unix_now() -> Option<u64> {
+    SystemTime::now()
+        .duration_since(UNIX_EPOCH)
+        .ok()
+        .map(|d| d.as_secs())
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    #[test]
+    fn test_health_aggregation() {
+        let health = HealthRegistry::new();
+        assert!(health.is_ready());
+
+        health.register(LAYERS);
+        health.register(WATCHER);
+        assert!(!health.is_ready());
+
+        health.set_up(LAYERS);
+        health.set_up(WATCHER);
+        assert!(health.is_ready());
+
+        // Degraded keeps serving, error is recorded per layer
+        health.record_failure(LAYERS, "a", "bad range");
+        assert!(health.is_ready());
+        let layers = &health.snapshot()[LAYERS];
+        assert_eq!(layers.status, HealthStatus::Degraded);
+        assert_eq!(layers.last_error.as_deref(), Some("bad range"));
+        assert!(layers.last_error_at.is_some());
+
+        // Another layer recovering does not hide the failing one
+        health.record_failure(LAYERS, "b", "unknown vid");
+        health.clear_failure(LAYERS, "b");
+        health.set_up(LAYERS);
+        let layers = &health.snapshot()[LAYERS];
+        assert_eq!(layers.status, HealthStatus::Degraded);
+        assert_eq!(layers.failing.keys().collect::<Vec<_>>(), vec!["a"]);
+
+        // Recovery keeps the last error for diagnosis
+        health.clear_failure(LAYERS, "a");
+        let layers = &health.snapshot()[LAYERS];
+        assert_eq!(layers.status, HealthStatus::Up);
+        assert!(layers.failing.is_empty());
+        assert_eq!(layers.last_error.as_deref(), Some("unknown vid"));
+
+        health.set_down(WATCHER, None);
+        assert!(!health.is_ready());
+    }
+}
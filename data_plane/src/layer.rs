@@ -3,7 +3,7 @@ use crate::error::{ExperimentError, Result};
 use arc_swap::ArcSwap;
 use parking_lot::RwLock;
 use serde::{Deserialize, Serialize};
-use std::collections::{HashMap, HashSet};
+use std::collections::{BTreeMap, HashMap, HashSet};
 use std::path::{Path, PathBuf};
 use std::sync::Arc;
 
@@ -161,6 +161,29 @@ impl Layer {
 
         None
     }
+
+    /// Number of slots covered by ranges
+    pub fn allocated_slots(&self) -> u32 {
+        self.ranges.iter().map(|r| r.end - r.start).sum()
+    }
+
+    /// Number of slots not covered by any range (remaining capacity)
+    pub fn free_slots(&self) -> u32 {
+        BUCKET_SIZE.saturating_sub(self.allocated_slots())
+    }
+
+    /// Slots occupied by each experiment in this layer (eid -> slots).
+    ///
+    /// Ranges whose vid is unknown to the catalog are not counted.
+    pub fn slots_by_experiment(&self, catalog: &ExperimentCatalog) -> BTreeMap<i64, u32> {
+        let mut slots: BTreeMap<i64, u32> = BTreeMap::new();
+        for r in &self.ranges {
+            if let Some(eid) = catalog.get_eid_by_vid(r.vid) {
+                *slots.entry(eid).or_insert(0) += r.end - r.start;
+            }
+        }
+        slots
+    }
 }
 
 fn normalize_services(services: Vec<String>) -> Vec<String> {
@@ -253,21 +276,216 @@ fn validate_and_sort_ranges(ranges: &mut Vec<BucketRange>) -> Result<()> {
     // Sort for determinism and to enable overlap check
     ranges.sort_by(|a, b| a.start.cmp(&b.start).then_with(|| a.end.cmp(&b.end)));
 
-    // Check overlap
-    for w in ranges.windows(2) {
-        let prev = &w[0];
-        let next = &w[1];
-        if next.start < prev.end {
-            return Err(ExperimentError::InvalidParameter(format!(
-                "Overlapping ranges: [{}, {}) overlaps [{}, {})",
-                prev.start, prev.end, next.start, next.end
-            )));
+    // Check overlap, collecting every conflicting pair so they can all be fixed
+    // at once. `active` holds the earlier ranges still open at `next.start`.
+    let mut conflicts = Vec::new();
+    let mut active: Vec<&BucketRange> = Vec::new();
+    for next in ranges.iter() {
+        active.retain(|prev| prev.end > next.start);
+        for prev in &active {
+            conflicts.push(format!(
+                "[{}, {}) vid {} overlaps [{}, {}) vid {}",
+                prev.start, prev.end, prev.vid, next.start, next.end, next.vid
+            ));
         }
+        active.push(next);
+    }
+
+    if !conflicts.is_empty() {
+        return Err(ExperimentError::InvalidParameter(format!(
+            "Overlapping ranges: {}",
+            conflicts.join("; ")
+        )));
     }
 
     Ok(())
 }
 
+/// Strictly check every layer file in `dir` against the catalog.
+///
+/// `LayerManager::load_all_layers` logs and skips bad files so the server can
+/// still start; this instead collects every problem (parse/range errors,
+/// layer_id not matching the file name, duplicate layer_ids, vids missing from
+/// the catalog, prerequisites not carried by a higher-priority layer) so they
+/// can be reported together by `--validate-config`.
+pub fn validate_layers_dir(dir: &Path, catalog: &ExperimentCatalog) -> Result<Vec<String>> {
+    let mut paths: Vec<PathBuf> = std::fs::read_dir(dir)?
+        .filter_map(|entry| entry.ok().map(|e| e.path()))
+        .filter(|path| {
+            path.is_file()
+                && matches!(
+                    path.extension().and_then(|s| s.to_str()),
+                    Some("json" | "yaml" | "yml")
+                )
+        })
+        .collect();
+    paths.sort();
+
+    let mut problems = Vec::new();
+    let mut seen: HashMap<String, PathBuf> = HashMap::new();
+    let mut layers = Vec::new();
+
+    for path in paths {
+        let layer = match Layer::from_file(&path) {
+            Ok(layer) => layer,
+            Err(e) => {
+                problems.push(format!("{}: {}", path.display(), e));
+                continue;
+            }
+        };
+
+        let file_stem = path.file_stem().map(|s| s.to_string_lossy().to_string());
+        if file_stem.as_deref() != Some(layer.layer_id.as_str()) {
+            problems.push(format!(
+                "{}: layer_id '{}' does not match file name (hot reload would reject it)",
+                path.display(),
+                layer.layer_id
+            ));
+        }
+
+        if let Some(other) = seen.get(&layer.layer_id) {
+            problems.push(format!(
+                "{}: duplicate layer_id '{}' (also in {})",
+                path.display(),
+                layer.layer_id,
+                other.display()
+            ));
+        } else {
+            seen.insert(layer.layer_id.clone(), path.clone());
+        }
+
+        let mut eids = Vec::new();
+        for range in &layer.ranges {
+            match catalog.get_eid_by_vid(range.vid) {
+                Some(eid) => eids.push(eid),
+                None => problems.push(format!(
+                    "{}: vid {} in range [{}, {}) not found in catalog",
+                    path.display(),
+                    range.vid,
+                    range.start,
+                    range.end
+                )),
+            }
+        }
+
+        // Rules of the experiments this layer serves; field types are only
+        // known at runtime, so only the type-independent checks apply here
+        eids.sort_unstable();
+        eids.dedup();
+        for eid in eids {
+            let rule = catalog.get_experiment(eid).and_then(|exp| exp.rule.as_ref());
+            if let Some(Err(e)) = rule.map(|rule| rule.check_structure()) {
+                problems.push(format!("{}: experiment {} rule: {}", path.display(), eid, e));
+            }
+        }
+
+        layers.push(layer);
+    }
+
+    problems.extend(
+        find_prerequisite_order_problems(layers.iter(), catalog)
+            .iter()
+            .map(|p| p.to_string()),
+    );
+
+    Ok(problems)
+}
+
+/// Enabled layers sharing the same priority for a service.
+///
+/// Evaluation order among them falls back to layer_id (ascending), which is
+/// deterministic but usually not what the author intended.
+#[derive(Debug, Clone, Serialize, PartialEq, Eq)]
+pub struct PriorityConflict {
+    pub service: String,
+    pub priority: i32,
+    pub layer_ids: Vec<String>,
+}
+
+/// A layer serving an experiment whose prerequisite is not carried by any
+/// enabled layer of strictly higher priority.
+///
+/// Prerequisites are checked against vids matched earlier in the same request,
+/// so such an experiment can never match.
+#[derive(Debug, Clone, Serialize, PartialEq, Eq)]
+pub struct PrerequisiteOrderProblem {
+    pub layer_id: String,
+    pub priority: i32,
+    pub eid: i64,
+    pub prerequisite: i64,
+}
+
+impl std::fmt::Display for PrerequisiteOrderProblem {
+    fn fmt(&self, f: &mut std::fmt::Formatter<'_>) -> std::fmt::Result {
+        write!(
+            f,
+            "layer '{}' (priority {}) serves experiment {}, but no layer with higher priority carries its prerequisite experiment {}; it can never match",
+            self.layer_id, self.priority, self.eid, self.prerequisite
+        )
+    }
+}
+
+/// Check that every prerequisite of each experiment served by an enabled layer
+/// is carried (by one of its required vids) by an enabled layer of strictly
+/// higher priority. Sorted by layer_id, then eid.
+pub fn find_prerequisite_order_problems<'a>(
+    layers: impl Iterator<Item = &'a Layer>,
+    catalog: &ExperimentCatalog,
+) -> Vec<PrerequisiteOrderProblem> {
+    let layers: Vec<&Layer> = layers.filter(|layer| layer.enabled).collect();
+    let mut problems = Vec::new();
+
+    for layer in &layers {
+        let mut eids: Vec<i64> = layer
+            .ranges
+            .iter()
+            .filter_map(|r| catalog.get_eid_by_vid(r.vid))
+            .collect();
+        eids.sort_unstable();
+        eids.dedup();
+
+        for eid in eids {
+            let Some(exp) = catalog.get_experiment(eid) else {
+                continue;
+            };
+            for prereq in &exp.prerequisites {
+                let carried = layers.iter().any(|other| {
+                    other.priority > layer.priority
+                        && other.ranges.iter().any(|r| prereq.vids.contains(&r.vid))
+                });
+                if !carried {
+                    problems.push(PrerequisiteOrderProblem {
+                        layer_id: layer.layer_id.clone(),
+                        priority: layer.priority,
+                        eid,
+                        prerequisite: prereq.eid,
+                    });
+                }
+            }
+        }
+    }
+
+    problems.sort_by(|a, b| a.layer_id.cmp(&b.layer_id).then_with(|| a.eid.cmp(&b.eid)));
+    problems
+}
+
+/// Loaded state of a single layer, for introspection
+#[derive(Debug, Clone, Serialize)]
+pub struct LayerStatus {
+    pub layer_id: String,
+    pub version: String,
+    pub priority: i32,
+    pub enabled: bool,
+    pub hash_key: String,
+    /// Effective salt (explicit or `{layer_id}_{version}`)
+    pub salt: String,
+    pub ranges: usize,
+    pub allocated_slots: u32,
+    pub file_path: PathBuf,
+    /// Number of previous versions available for rollback
+    pub rollback_versions: usize,
+}
+
 /// Layer version tracking
 #[derive(Debug, Clone)]
 struct LayerVersion {
@@ -286,6 +504,12 @@ pub struct LayerManager {
     /// service -> [layer_id] (sorted by priority)
     service_index: Arc<ArcSwap<HashMap<String, Vec<String>>>>,
 
+    /// Priority ties detected during the last index rebuild
+    priority_conflicts: Arc<ArcSwap<Vec<PriorityConflict>>>,
+
+    /// Prerequisites not carried by a higher-priority layer, from the last index rebuild
+    prerequisite_problems: Arc<ArcSwap<Vec<PrerequisiteOrderProblem>>>,
+
     /// Rollback history: layer_id -> previous versions
     history: Arc<RwLock<HashMap<String, Vec<Arc<Layer>>>>>,
 }
@@ -296,6 +520,8 @@ impl LayerManager {
             layers_dir,
             layers: Arc::new(ArcSwap::from_pointee(HashMap::new())),
             service_index: Arc::new(ArcSwap::from_pointee(HashMap::new())),
+            priority_conflicts: Arc::new(ArcSwap::from_pointee(Vec::new())),
+            prerequisite_problems: Arc::new(ArcSwap::from_pointee(Vec::new())),
             history: Arc::new(RwLock::new(HashMap::new())),
         }
     }
@@ -340,15 +566,52 @@ impl LayerManager {
 
         // Sort by priority (descending) and layer_id (for determinism)
         let mut service_index: HashMap<String, Vec<String>> = HashMap::new();
+        let mut conflicts: Vec<PriorityConflict> = Vec::new();
         for (service, mut layer_list) in service_to_layers {
             layer_list.sort_by(|a, b| b.1.cmp(&a.1).then_with(|| a.0.cmp(&b.0)));
+
+            // Detect runs of equal priority (adjacent after sorting)
+            let mut i = 0;
+            while i < layer_list.len() {
+                let mut j = i + 1;
+                while j < layer_list.len() && layer_list[j].1 == layer_list[i].1 {
+                    j += 1;
+                }
+                if j - i > 1 {
+                    conflicts.push(PriorityConflict {
+                        service: service.clone(),
+                        priority: layer_list[i].1,
+                        layer_ids: layer_list[i..j].iter().map(|(id, _)| id.clone()).collect(),
+                    });
+                }
+                i = j;
+            }
+
             service_index.insert(
                 service,
                 layer_list.into_iter().map(|(id, _)| id).collect(),
             );
         }
 
+        conflicts.sort_by(|a, b| a.service.cmp(&b.service).then_with(|| b.priority.cmp(&a.priority)));
+        for c in &conflicts {
+            tracing::warn!(
+                "Layers {:?} share priority {} for service {}, evaluated in layer_id order",
+                c.layer_ids,
+                c.priority,
+                c.service
+            );
+        }
+
+        let prerequisite_problems =
+            find_prerequisite_order_problems(layers_map.values().map(|v| v.layer.as_ref()), catalog);
+        for p in &prerequisite_problems {
+            tracing::warn!("{}", p);
+        }
+
         self.service_index.store(Arc::new(service_index));
+        self.priority_conflicts.store(Arc::new(conflicts));
+        self.prerequisite_problems.store(Arc::new(prerequisite_problems));
     }
 
     /// Load all layers from directory
@@ -521,6 +784,59 @@ impl LayerManager {
         self.layers.load().keys().cloned().collect()
     }
 
+    /// Number of loaded layers (including disabled)
+    pub fn layer_count(&self) -> usize {
+        self.layers.load().len()
+    }
+
+    /// Number of enabled layers
+    pub fn enabled_layer_count(&self) -> usize {
+        self.layers.load().values().filter(|v| v.layer.enabled).count()
+    }
+
+    /// Get loaded state of every layer, sorted by layer_id
+    pub fn get_layer_statuses(&self) -> Vec<LayerStatus> {
+        let layers = self.layers.load();
+        let history = self.history.read();
+
+        let mut statuses: Vec<LayerStatus> = layers
+            .iter()
+            .map(|(layer_id, v)| LayerStatus {
+                layer_id: layer_id.clone(),
+                version: v.layer.version.clone(),
+                priority: v.layer.priority,
+                enabled: v.layer.enabled,
+                hash_key: v.layer.hash_key.clone(),
+                salt: v.layer.get_salt(),
+                ranges: v.layer.ranges.len(),
+                allocated_slots: v.layer.allocated_slots(),
+                file_path: v.file_path.clone(),
+                rollback_versions: history.get(layer_id).map_or(0, Vec::len),
+            })
+            .collect();
+        statuses.sort_by(|a, b| a.layer_id.cmp(&b.layer_id));
+        statuses
+    }
+
+    /// Get service → layer_ids index (layers in evaluation order)
+    pub fn get_service_index(&self) -> BTreeMap<String, Vec<String>> {
+        self.service_index
+            .load()
+            .iter()
+            .map(|(service, layer_ids)| (service.clone(), layer_ids.clone()))
+            .collect()
+    }
+
+    /// Get priority ties between enabled layers of the same service
+    pub fn get_priority_conflicts(&self) -> Vec<PriorityConflict> {
+        (**self.priority_conflicts.load()).clone()
+    }
+
+    /// Get experiments whose prerequisites no higher-priority layer carries
+    pub fn get_prerequisite_problems(&self) -> Vec<PrerequisiteOrderProblem> {
+        (**self.prerequisite_problems.load()).clone()
+    }
+
     /// Get layers for a specific service (using inverted index)
     pub fn get_layers_for_service(&self, service: &str) -> Vec<Arc<Layer>> {
         let service_index = self.service_index.load();
@@ -594,6 +910,61 @@ mod tests {
         assert!(format!("{}", err).contains("Overlapping ranges"));
     }
 
+    #[test]
+    fn test_ranges_overlap_reports_all_conflicts() {
+        let mut ranges = vec![
+            BucketRange {
+                start: 0,
+                end: 100,
+                vid: 1,
+            },
+            BucketRange {
+                start: 50,
+                end: 60,
+                vid: 2,
+            },
+            BucketRange {
+                start: 90,
+                end: 200,
+                vid: 3,
+            },
+            BucketRange {
+                start: 300,
+                end: 400,
+                vid: 4,
+            },
+        ];
+
+        let msg = format!("{}", validate_and_sort_ranges(&mut ranges).unwrap_err());
+        assert!(msg.contains("[0, 100) vid 1 overlaps [50, 60) vid 2"));
+        assert!(msg.contains("[0, 100) vid 1 overlaps [90, 200) vid 3"));
+        assert!(!msg.contains("vid 4"));
+
+        // A short range nested after a long one still conflicts with both
+        let mut ranges = vec![
+            BucketRange {
+                start: 0,
+                end: 100,
+                vid: 1,
+            },
+            BucketRange {
+                start: 50,
+                end: 300,
+                vid: 2,
+            },
+            BucketRange {
+                start: 90,
+                end: 95,
+                vid: 3,
+            },
+        ];
+
+        let msg = format!("{}", validate_and_sort_ranges(&mut ranges).unwrap_err());
+        assert!(msg.contains("[0, 100) vid 1 overlaps [50, 300) vid 2"));
+        assert!(msg.contains("[0, 100) vid 1 overlaps [90, 95) vid 3"));
+        assert!(msg.contains("[50, 300) vid 2 overlaps [90, 95) vid 3"));
+    }
+
     #[test]
     fn test_ranges_end_bound_error() {
         let mut ranges = vec![BucketRange {
@@ -606,6 +977,70 @@ mod tests {
         assert!(format!("{}", err).contains("exceeds BUCKET_SIZE"));
     }
 
+    #[test]
+    fn test_layer_capacity() {
+        use crate::catalog::ExperimentDef;
+
+        let temp_dir = TempDir::new().unwrap();
+        let exp_def = ExperimentDef {
+            eid: 100,
+            service: "svc".to_string(),
+            rule: None,
+            variants: vec![
+                VariantDef {
+                    vid: 1001,
+                    params: serde_json::json!({}),
+                },
+                VariantDef {
+                    vid: 1002,
+                    params: serde_json::json!({}),
+                },
+            ],
+            overrides: HashMap::new(),
+            prerequisites: vec![],
+        };
+        std::fs::write(
+            temp_dir.path().join("100.json"),
+            serde_json::to_string_pretty(&exp_def).unwrap(),
+        )
+        .unwrap();
+        let catalog = ExperimentCatalog::load_from_dir(temp_dir.path().to_path_buf()).unwrap();
+
+        let layer = Layer {
+            layer_id: "test".to_string(),
+            version: "v1".to_string(),
+            priority: 100,
+            hash_key: "user_id".to_string(),
+            salt: None,
+            services: vec![],
+            ranges: vec![
+                BucketRange {
+                    start: 0,
+                    end: 1000,
+                    vid: 1001,
+                },
+                BucketRange {
+                    start: 1000,
+                    end: 2000,
+                    vid: 1002,
+                },
+                BucketRange {
+                    start: 5000,
+                    end: 5500,
+                    vid: 9999,
+                },
+            ],
+            enabled: true,
+        };
+
+        assert_eq!(layer.allocated_slots(), 2500);
+        assert_eq!(layer.free_slots(), BUCKET_SIZE - 2500);
+
+        let slots = layer.slots_by_experiment(&catalog);
+        assert_eq!(slots.len(), 1);
+        assert_eq!(slots.get(&100), Some(&2000));
+    }
+
     #[tokio::test]
     async fn test_layer_manager_load() {
         use crate::catalog::ExperimentDef;
@@ -624,6 +1059,8 @@ mod tests {
                 vid: 1001,
                 params: serde_json::json!({}),
             }],
+            overrides: HashMap::new(),
+            prerequisites: vec![],
         };
         std::fs::write(
             groups_dir.join("100.json"),
@@ -656,4 +1093,302 @@ mod tests {
         assert_eq!(loaded.layer_id, "test");
         assert_eq!(loaded.version, "v1");
     }
+
+    #[tokio::test]
+    async fn test_priority_conflicts() {
+        use crate::catalog::ExperimentDef;
+
+        let temp_dir = TempDir::new().unwrap();
+        let layers_dir = temp_dir.path().join("layers");
+        let groups_dir = temp_dir.path().join("groups");
+        std::fs::create_dir_all(&layers_dir).unwrap();
+        std::fs::create_dir_all(&groups_dir).unwrap();
+
+        let exp_def = ExperimentDef {
+            eid: 100,
+            service: "svc".to_string(),
+            rule: None,
+            variants: vec![
+                VariantDef {
+                    vid: 1001,
+                    params: serde_json::json!({}),
+                },
+                VariantDef {
+                    vid: 1002,
+                    params: serde_json::json!({}),
+                },
+                VariantDef {
+                    vid: 1003,
+                    params: serde_json::json!({}),
+                },
+            ],
+            overrides: HashMap::new(),
+            prerequisites: vec![],
+        };
+        std::fs::write(
+            groups_dir.join("100.json"),
+            serde_json::to_string_pretty(&exp_def).unwrap(),
+        )
+        .unwrap();
+        let catalog = ExperimentCatalog::load_from_dir(groups_dir).unwrap();
+
+        for (layer_id, priority, vid) in [("b", 100, 1001), ("a", 100, 1002), ("c", 200, 1003)] {
+            let layer = Layer {
+                layer_id: layer_id.to_string(),
+                version: "v1".to_string(),
+                priority,
+                hash_key: "user_id".to_string(),
+                salt: None,
+                services: vec![],
+                ranges: vec![BucketRange {
+                    start: 0,
+                    end: 1,
+                    vid,
+                }],
+                enabled: true,
+            };
+            std::fs::write(
+                layers_dir.join(format!("{}.json", layer_id)),
+                serde_json::to_string_pretty(&layer).unwrap(),
+            )
+            .unwrap();
+        }
+
+        let manager = LayerManager::new(layers_dir);
+        manager.load_all_layers(&catalog).await.unwrap();
+
+        assert_eq!(
+            manager.get_priority_conflicts(),
+            vec![PriorityConflict {
+                service: "svc".to_string(),
+                priority: 100,
+                layer_ids: vec!["a".to_string(), "b".to_string()],
+            }]
+        );
+
+        // Tie is broken by layer_id
+        let order: Vec<String> = manager
+            .get_layers_for_service("svc")
+            .iter()
+            .map(|l| l.layer_id.clone())
+            .collect();
+        assert_eq!(order, vec!["c", "a", "b"]);
+
+        manager.remove_layer("b", &catalog).await.unwrap();
+        assert!(manager.get_priority_conflicts().is_empty());
+    }
+
+    #[test]
+    fn test_validate_layers_dir() {
+        use crate::catalog::ExperimentDef;
+
+        let temp_dir = TempDir::new().unwrap();
+        let layers_dir = temp_dir.path().join("layers");
+        let groups_dir = temp_dir.path().join("groups");
+        std::fs::create_dir_all(&layers_dir).unwrap();
+        std::fs::create_dir_all(&groups_dir).unwrap();
+
+        let exp_def = ExperimentDef {
+            eid: 100,
+            service: "svc".to_string(),
+            rule: None,
+            variants: vec![VariantDef {
+                vid: 1001,
+                params: serde_json::json!({}),
+            }],
+            overrides: HashMap::new(),
+            prerequisites: vec![],
+        };
+        std::fs::write(
+            groups_dir.join("100.json"),
+            serde_json::to_string_pretty(&exp_def).unwrap(),
+        )
+        .unwrap();
+        let catalog = ExperimentCatalog::load_from_dir(groups_dir).unwrap();
+
+        let layer = |layer_id: &str, vid: i64| Layer {
+            layer_id: layer_id.to_string(),
+            version: "v1".to_string(),
+            priority: 100,
+            hash_key: "user_id".to_string(),
+            salt: None,
+            services: vec![],
+            ranges: vec![BucketRange {
+                start: 0,
+                end: 100,
+                vid,
+            }],
+            enabled: true,
+        };
+        for (file, layer) in [
+            ("good.json", layer("good", 1001)),
+            ("renamed.json", layer("other", 1001)),
+            ("orphan.json", layer("orphan", 9999)),
+        ] {
+            std::fs::write(
+                layers_dir.join(file),
+                serde_json::to_string_pretty(&layer).unwrap(),
+            )
+            .unwrap();
+        }
+        std::fs::write(layers_dir.join("broken.json"), "{not json").unwrap();
+        std::fs::write(layers_dir.join("notes.txt"), "ignored").unwrap();
+
+        let problems = validate_layers_dir(&layers_dir, &catalog).unwrap();
+
+        assert_eq!(problems.len(), 3, "{:?}", problems);
+        assert!(problems[0].contains("broken.json"));
+        assert!(problems[1].contains("orphan.json") && problems[1].contains("vid 9999"));
+        assert!(problems[2].contains("renamed.json") && problems[2].contains("does not match file name"));
+    }
+
+    #[tokio::test]
+    async fn test_prerequisite_order_problems() {
+        use crate::catalog::{ExperimentDef, Prerequisite};
+
+        let temp_dir = TempDir::new().unwrap();
+        let groups_dir = temp_dir.path().join("groups");
+        std::fs::create_dir_all(&groups_dir).unwrap();
+
+        // 200 requires variant 1001 of 100
+        for (eid, vid, prerequisites) in [
+            (100, 1001, vec![]),
+            (200, 2001, vec![Prerequisite { eid: 100, vids: vec![1001] }]),
+        ] {
+            let exp_def = ExperimentDef {
+                eid,
+                service: "svc".to_string(),
+                rule: None,
+                variants: vec![VariantDef {
+                    vid,
+                    params: serde_json::json!({}),
+                }],
+                overrides: HashMap::new(),
+                prerequisites,
+            };
+            std::fs::write(
+                groups_dir.join(format!("{}.json", eid)),
+                serde_json::to_string_pretty(&exp_def).unwrap(),
+            )
+            .unwrap();
+        }
+        let catalog = ExperimentCatalog::load_from_dir(groups_dir).unwrap();
+
+        let layer = |layer_id: &str, priority: i32, ranges: Vec<(u32, u32, i64)>| Layer {
+            layer_id: layer_id.to_string(),
+            version: "v1".to_string(),
+            priority,
+            hash_key: "user_id".to_string(),
+            salt: None,
+            services: vec![],
+            ranges: ranges
+                .into_iter()
+                .map(|(start, end, vid)| BucketRange { start, end, vid })
+                .collect(),
+            enabled: true,
+        };
+
+        let check = |layers: Vec<Layer>| {
+            let layers_dir = TempDir::new().unwrap();
+            for layer in &layers {
+                std::fs::write(
+                    layers_dir.path().join(format!("{}.json", layer.layer_id)),
+                    serde_json::to_string_pretty(layer).unwrap(),
+                )
+                .unwrap();
+            }
+            (
+                find_prerequisite_order_problems(layers.iter(), &catalog),
+                validate_layers_dir(layers_dir.path(), &catalog).unwrap(),
+            )
+        };
+
+        // Prerequisite in a higher-priority layer
+        let (problems, reported) = check(vec![
+            layer("base", 200, vec![(0, 100, 1001)]),
+            layer("gated", 100, vec![(0, 100, 2001)]),
+        ]);
+        assert!(problems.is_empty());
+        assert!(reported.is_empty(), "{:?}", reported);
+
+        // Same layer: ranges are exclusive, so no unit is in both
+        let (problems, reported) = check(vec![layer("shared", 100, vec![(0, 50, 1001), (50, 100, 2001)])]);
+        assert_eq!(
+            problems,
+            vec![PrerequisiteOrderProblem {
+                layer_id: "shared".to_string(),
+                priority: 100,
+                eid: 200,
+                prerequisite: 100,
+            }]
+        );
+        assert_eq!(reported, vec![problems[0].to_string()]);
+
+        // Lower and equal priority are both evaluated too late
+        for base_priority in [50, 100] {
+            let (problems, reported) = check(vec![
+                layer("a_base", base_priority, vec![(0, 100, 1001)]),
+                layer("gated", 100, vec![(0, 100, 2001)]),
+            ]);
+            assert_eq!(problems.len(), 1);
+            assert_eq!(problems[0].layer_id, "gated");
+            assert_eq!(reported.len(), 1);
+        }
+
+        // The manager reports the same after loading
+        let layers_dir = temp_dir.path().join("layers");
+        std::fs::create_dir_all(&layers_dir).unwrap();
+        let lower = layer("base", 50, vec![(0, 100, 1001)]);
+        let gated = layer("gated", 100, vec![(0, 100, 2001)]);
+        for layer in [&lower, &gated] {
+            std::fs::write(
+                layers_dir.join(format!("{}.json", layer.layer_id)),
+                serde_json::to_string_pretty(layer).unwrap(),
+            )
+            .unwrap();
+        }
+        let manager = LayerManager::new(layers_dir);
+        manager.load_all_layers(&catalog).await.unwrap();
+        assert_eq!(manager.get_prerequisite_problems().len(), 1);
+    }
+
+    #[tokio::test]
+    async fn test_layer_statuses() {
+        let temp_dir = TempDir::new().unwrap();
+        let layers_dir = temp_dir.path().join("layers");
+        std::fs::create_dir_all(&layers_dir).unwrap();
+        let catalog = ExperimentCatalog::load_from_dir(temp_dir.path().join("groups")).unwrap();
+
+        let mut layer = Layer {
+            layer_id: "layer1".to_string(),
+            version: "v1".to_string(),
+            priority: 100,
+            hash_key: "user_id".to_string(),
+            salt: None,
+            services: vec![],
+            ranges: vec![BucketRange {
+                start: 0,
+                end: 2500,
+                vid: 1001,
+            }],
+            enabled: true,
+        };
+        let path = layers_dir.join("layer1.json");
+        std::fs::write(&path, serde_json::to_string_pretty(&layer).unwrap()).unwrap();
+
+        let manager = LayerManager::new(layers_dir);
+        manager.load_all_layers(&catalog).await.unwrap();
+
+        layer.version = "v2".to_string();
+        std::fs::write(&path, serde_json::to_string_pretty(&layer).unwrap()).unwrap();
+        manager.load_layer("layer1", &path, &catalog).await.unwrap();
+
+        let statuses = manager.get_layer_statuses();
+        assert_eq!(statuses.len(), 1);
+        assert_eq!(statuses[0].version, "v2");
+        assert_eq!(statuses[0].salt, "layer1_v2");
+        assert_eq!(statuses[0].allocated_slots, 2500);
+        assert_eq!(statuses[0].file_path, path);
+        assert_eq!(statuses[0].rollback_versions, 1);
+    }
 }
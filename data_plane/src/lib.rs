@@ -2,9 +2,11 @@ pub mod catalog;
 pub mod config;
 pub mod error;
 pub mod hash;
+pub mod health;
 pub mod layer;
 pub mod merge;
 pub mod metrics;
 pub mod rule;
+pub mod segment;
 pub mod server;
 pub mod watcher;
@@ -4,23 +4,41 @@ mod error;
 mod layer;
 mod merge;
 mod hash;
+mod health;
 mod rule;
+mod segment;
 mod server;
 mod watcher;
 mod metrics;
 
 use anyhow::Result;
+use health::{HealthRegistry, LAYERS, WATCHER};
 use std::sync::Arc;
-use tracing_subscriber::{layer::SubscriberExt, util::SubscriberInitExt};
+use std::time::Duration;
+use tokio::sync::oneshot;
+use tracing_subscriber::{layer::SubscriberExt, reload, util::SubscriberInitExt};
+
+/// How long in-flight HTTP requests get to finish after a shutdown signal
+const SHUTDOWN_TIMEOUT: Duration = Duration::from_secs(30);
 
 #[tokio::main]
 async fn main() -> Result<()> {
-    // Initialize tracing
+    if std::env::args().any(|arg| arg == "--validate-config") {
+        // Warnings only, on stderr: stdout carries the result line CI reads
+        tracing_subscriber::fmt()
+            .with_env_filter(tracing_subscriber::EnvFilter::new("warn"))
+            .with_writer(std::io::stderr)
+            .init();
+        return validate_config().await;
+    }
+
+    // Initialize tracing (filter is reloadable via PUT /admin/loglevel)
+    let (filter, log_filter) = reload::Layer::new(
+        tracing_subscriber::EnvFilter::try_from_default_env()
+            .unwrap_or_else(|_| "experiment_data_plane=info,tower_http=debug".into()),
+    );
     tracing_subscriber::registry()
-        .with(
-            tracing_subscriber::EnvFilter::try_from_default_env()
-                .unwrap_or_else(|_| "experiment_data_plane=info,tower_http=debug".into()),
-        )
+        .with(filter)
         .with(tracing_subscriber::fmt::layer())
         .init();
 
@@ -32,8 +50,17 @@ async fn main() -> Result<()> {
 
     // Step 1: Load experiment catalog first (happens-before layer loading)
     tracing::info!("Loading experiment catalog from {:?}", config.experiments_dir);
-    let catalog = Arc::new(catalog::ExperimentCatalog::load_from_dir(config.experiments_dir.clone())?);
-    tracing::info!("Experiment catalog loaded: {} experiments", catalog.len());
+    // (segments are attached before any rule can reference them)
+    let segments = segment::SegmentSet::load_from_dir(config.segments_dir.clone())?;
+    let catalog = Arc::new(
+        catalog::ExperimentCatalog::load_from_dir(config.experiments_dir.clone())?
+            .with_segments(segments)?,
+    );
+    tracing::info!(
+        "Experiment catalog loaded: {} experiments, {} segments",
+        catalog.len(),
+        catalog.segments().len()
+    );
 
     // Step 2: Initialize layer manager
     let layer_manager = Arc::new(layer::LayerManager::new(config.layers_dir.clone()));
@@ -42,34 +69,158 @@ async fn main() -> Result<()> {
     layer_manager.load_all_layers(&catalog).await?;
     tracing::info!("Initial layers loaded");
 
+    let health = Arc::new(HealthRegistry::new());
+    health.set_up(LAYERS);
+    health.register(WATCHER);
+
     // Start file watcher for hot reload (layers only)
+    let (watcher_stop_tx, watcher_stop_rx) = oneshot::channel();
     let watcher_manager = layer_manager.clone();
     let watcher_catalog = catalog.clone();
-    let watcher_handle = tokio::spawn(async move {
-        if let Err(e) = watcher::watch_layers(watcher_manager, watcher_catalog).await {
+    let watcher_health = health.clone();
+    let mut watcher_handle = tokio::spawn(async move {
+        if let Err(e) = watcher::watch_layers(
+            watcher_manager,
+            watcher_catalog,
+            watcher_health.clone(),
+            watcher_stop_rx,
+        )
+        .await
+        {
             tracing::error!("Watcher error: {}", e);
+            watcher_health.set_down(WATCHER, Some(e.to_string()));
         }
     });
 
     // Start HTTP server
-    let server_handle = tokio::spawn(async move {
-        if let Err(e) = server::run_server(config, layer_manager, catalog).await {
+    let (server_stop_tx, server_stop_rx) = oneshot::channel::<()>();
+    let mut server_handle = tokio::spawn(async move {
+        let shutdown = async move {
+            let _ = server_stop_rx.await;
+        };
+        if let Err(e) =
+            server::run_server(config, layer_manager, catalog, health, log_filter, shutdown).await
+        {
             tracing::error!("Server error: {}", e);
         }
     });
 
-    // Wait for both tasks
+    // Wait for either task to exit or a shutdown signal
     tokio::select! {
-        _ = watcher_handle => {
+        _ = &mut watcher_handle => {
             tracing::warn!("Watcher stopped");
         }
-        _ = server_handle => {
+        _ = &mut server_handle => {
             tracing::warn!("Server stopped");
         }
-        _ = tokio::signal::ctrl_c() => {
+        _ = shutdown_signal() => {
             tracing::info!("Received shutdown signal");
         }
     }
 
+    // Ordered shutdown: stop config reloads first (finishing any reload in
+    // progress), then stop accepting connections and drain in-flight requests.
+    let _ = watcher_stop_tx.send(());
+    if !watcher_handle.is_finished() {
+        let _ = watcher_handle.await;
+    }
+
+    let _ = server_stop_tx.send(());
+    if !server_handle.is_finished()
+        && tokio::time::timeout(SHUTDOWN_TIMEOUT, server_handle).await.is_err()
+    {
+        tracing::warn!(
+            "In-flight requests did not finish within {:?}, exiting anyway",
+            SHUTDOWN_TIMEOUT
+        );
+    }
+
+    tracing::info!("Shutdown complete");
+
     Ok(())
 }
+
+/// Resolve on Ctrl-C, or SIGTERM on unix (what Kubernetes sends on pod deletion)
+async fn shutdown_signal() {
+    let ctrl_c = async {
+        let _ = tokio::signal::ctrl_c().await;
+    };
+
+    #[cfg(unix)]
+    let terminate = async {
+        match tokio::signal::unix::signal(tokio::signal::unix::SignalKind::terminate()) {
+            Ok(mut signal) => {
+                signal.recv().await;
+            }
+            Err(e) => {
+                tracing::error!("Failed to install SIGTERM handler: {}", e);
+                std::future::pending::<()>().await;
+            }
+        }
+    };
+
+    #[cfg(not(unix))]
+    let terminate = std::future::pending::<()>();
+
+    tokio::select! {
+        _ = ctrl_c => {}
+        _ = terminate => {}
+    }
+}
+
+/// `--validate-config`: strictly load config, catalog and layers, report every
+/// problem and exit non-zero if there are any, so CI/CD catches them before deploy.
+async fn validate_config() -> Result<()> {
+    let mut problems = Vec::new();
+
+    let config = config::Config::from_env()?;
+    for (name, dir) in [
+        ("EXPERIMENTS_DIR", &config.experiments_dir),
+        ("LAYERS_DIR", &config.layers_dir),
+    ] {
+        if !dir.is_dir() {
+            problems.push(format!("{} {:?} is not a directory", name, dir));
+        }
+    }
+
+    if problems.is_empty() {
+        let catalog = catalog::ExperimentCatalog::load_from_dir(config.experiments_dir.clone())
+            .and_then(|catalog| {
+                catalog.with_segments(segment::SegmentSet::load_from_dir(config.segments_dir.clone())?)
+            });
+        match catalog {
+            Ok(catalog) => {
+                problems.extend(layer::validate_layers_dir(&config.layers_dir, &catalog)?);
+
+                // Priority ties are legal (layer_id breaks them) but worth a warning
+                let layer_manager = layer::LayerManager::new(config.layers_dir.clone());
+                layer_manager.load_all_layers(&catalog).await?;
+                for c in layer_manager.get_priority_conflicts() {
+                    eprintln!(
+                        "warning: layers {:?} share priority {} for service {}",
+                        c.layer_ids, c.priority, c.service
+                    );
+                }
+
+                if problems.is_empty() {
+                    println!(
+                        "Configuration OK: {} experiments, {} layers",
+                        catalog.len(),
+                        layer_manager.layer_count()
+                    );
+                    return Ok(());
+                }
+            }
+            Err(e) => problems.push(format!(
+                "catalog {:?} / segments {:?}: {}",
+                config.experiments_dir, config.segments_dir, e
+            )),
+        }
+    }
+
+    for problem in &problems {
+        eprintln!("error: {}", problem);
+    }
+    eprintln!("Configuration invalid: {} problem(s)", problems.len());
+    std::process::exit(1);
+}
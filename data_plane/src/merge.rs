@@ -1,7 +1,7 @@
 use crate::catalog::ExperimentCatalog;
 use crate::error::{ExperimentError, Result};
 use crate::hash::hash_to_bucket;
-use crate::layer::LayerManager;
+use crate::layer::{Layer, LayerManager};
 use crate::rule::FieldType;
 use serde_json::Value;
 use std::collections::HashMap;
@@ -30,6 +30,119 @@ pub struct ExperimentResponse {
     pub results: HashMap<String, ServiceResult>,
 }
 
+/// Per-layer trace returned by the dry-run evaluation
+#[derive(Debug, Clone, serde::Serialize)]
+pub struct LayerTrace {
+    pub layer_id: String,
+    pub priority: i32,
+    pub hash_key: String,
+    #[serde(skip_serializing_if = "Option::is_none")]
+    pub bucket: Option<u32>,
+    #[serde(skip_serializing_if = "Option::is_none")]
+    pub vid: Option<i64>,
+    #[serde(skip_serializing_if = "Option::is_none")]
+    pub eid: Option<i64>,
+    /// matched | forced | no_hash_key | unassigned | unknown_vid | service_mismatch |
+    /// prerequisite_unmet | rule_rejected
+    pub outcome: &'static str,
+    /// Prerequisite experiment the unit was not in
+    #[serde(skip_serializing_if = "Option::is_none")]
+    pub unmet_prerequisite: Option<i64>,
+    #[serde(skip_serializing_if = "Option::is_none")]
+    pub rule_error: Option<String>,
+    /// Rule nodes that decided `matched` / `rule_rejected`, e.g. `and[1].field(country eq)`
+    #[serde(skip_serializing_if = "Vec::is_empty")]
+    pub rule_branches: Vec<String>,
+}
+
+/// Per-service dry-run result: the merged result plus how each layer resolved
+#[derive(Debug, Clone, serde::Serialize)]
+pub struct ServiceEvaluation {
+    #[serde(flatten)]
+    pub result: ServiceResult,
+    pub layers: Vec<LayerTrace>,
+}
+
+/// Dry-run response
+#[derive(Debug, Clone, serde::Serialize)]
+pub struct EvaluateResponse {
+    pub results: HashMap<String, ServiceEvaluation>,
+}
+
+/// How a single layer resolved for a service
+enum LayerOutcome<'a> {
+    /// Hash key missing from context, or not a string/number
+    NoHashKey,
+    /// Bucket falls into a hole of the layer's ranges
+    Unassigned { bucket: u32 },
+    /// vid not present in catalog
+    UnknownVid { bucket: u32, vid: i64 },
+    /// Variant belongs to another service
+    ServiceMismatch { bucket: u32, vid: i64, eid: i64 },
+    /// Unit is not in a required variant of the prerequisite experiment
+    PrerequisiteUnmet { bucket: u32, vid: i64, eid: i64, prerequisite: i64 },
+    /// Experiment rule did not pass (error is set when evaluation failed)
+    RuleRejected { bucket: u32, vid: i64, eid: i64, error: Option<String>, branches: Vec<String> },
+    Matched {
+        bucket: u32,
+        vid: i64,
+        eid: i64,
+        forced: bool,
+        params: &'a Value,
+        branches: Vec<String>,
+    },
+}
+
+impl LayerTrace {
+    fn new(layer: &Layer, outcome: &LayerOutcome) -> Self {
+        let unmet_prerequisite = match outcome {
+            LayerOutcome::PrerequisiteUnmet { prerequisite, .. } => Some(*prerequisite),
+            _ => None,
+        };
+
+        let (bucket, vid, eid, outcome, rule_error, rule_branches) = match outcome {
+            LayerOutcome::NoHashKey => (None, None, None, "no_hash_key", None, Vec::new()),
+            LayerOutcome::Unassigned { bucket } => {
+                (Some(*bucket), None, None, "unassigned", None, Vec::new())
+            }
+            LayerOutcome::UnknownVid { bucket, vid } => {
+                (Some(*bucket), Some(*vid), None, "unknown_vid", None, Vec::new())
+            }
+            LayerOutcome::ServiceMismatch { bucket, vid, eid } => {
+                (Some(*bucket), Some(*vid), Some(*eid), "service_mismatch", None, Vec::new())
+            }
+            LayerOutcome::PrerequisiteUnmet { bucket, vid, eid, .. } => {
+                (Some(*bucket), Some(*vid), Some(*eid), "prerequisite_unmet", None, Vec::new())
+            }
+            LayerOutcome::RuleRejected { bucket, vid, eid, error, branches } => (
+                Some(*bucket),
+                Some(*vid),
+                Some(*eid),
+                "rule_rejected",
+                error.clone(),
+                branches.clone(),
+            ),
+            LayerOutcome::Matched { bucket, vid, eid, forced, branches, .. } => {
+                let outcome = if *forced { "forced" } else { "matched" };
+                (Some(*bucket), Some(*vid), Some(*eid), outcome, None, branches.clone())
+            }
+        };
+
+        Self {
+            layer_id: layer.layer_id.clone(),
+            priority: layer.priority,
+            hash_key: layer.hash_key.clone(),
+            bucket,
+            vid,
+            eid,
+            outcome,
+            unmet_prerequisite,
+            rule_error,
+            rule_branches,
+        }
+    }
+}
+
 /// Merge multiple layers for multiple services
 pub fn merge_layers_batch(
     request: &ExperimentRequest,
@@ -41,19 +154,45 @@ pub fn merge_layers_batch(
 
     for service in &request.services {
         let service_result =
-            merge_layers_for_service(service, request, layer_manager, catalog, field_types)?;
+            merge_layers_for_service(service, request, layer_manager, catalog, field_types, None)?;
         results.insert(service.clone(), service_result);
     }
 
     Ok(ExperimentResponse { results })
 }
 
+/// Dry-run of `merge_layers_batch` that also reports how every layer resolved
+pub fn evaluate_batch(
+    request: &ExperimentRequest,
+    layer_manager: &LayerManager,
+    catalog: &ExperimentCatalog,
+    field_types: &HashMap<String, FieldType>,
+) -> Result<EvaluateResponse> {
+    let mut results = HashMap::new();
+
+    for service in &request.services {
+        let mut layers = Vec::new();
+        let result = merge_layers_for_service(
+            service,
+            request,
+            layer_manager,
+            catalog,
+            field_types,
+            Some(&mut layers),
+        )?;
+        results.insert(service.clone(), ServiceEvaluation { result, layers });
+    }
+
+    Ok(EvaluateResponse { results })
+}
+
 fn merge_layers_for_service(
     service: &str,
     request: &ExperimentRequest,
     layer_manager: &LayerManager,
     catalog: &ExperimentCatalog,
     field_types: &HashMap<String, FieldType>,
+    mut trace: Option<&mut Vec<LayerTrace>>,
 ) -> Result<ServiceResult> {
     let mut final_params = serde_json::Map::new();
     let mut matched_vids = Vec::new();
@@ -70,85 +209,157 @@ fn merge_layers_for_service(
     };
 
     for layer in layers {
-        let hash_key_value = match request.context.get(&layer.hash_key) {
-            Some(Value::String(s)) => s.as_str(),
-            Some(Value::Number(n)) => {
-                tracing::warn!(
-                    "Hash key '{}' is a number, converting to string for layer '{}'",
-                    layer.hash_key,
-                    layer.layer_id
-                );
-                &n.to_string()
-            }
-            Some(_) => {
-                tracing::warn!(
-                    "Hash key '{}' must be a string or number for layer '{}', skipping",
-                    layer.hash_key,
-                    layer.layer_id
-                );
-                continue;
-            }
-            None => {
-                tracing::warn!(
-                    "Hash key '{}' not found in context for layer '{}', skipping",
-                    layer.hash_key,
-                    layer.layer_id
-                );
-                continue;
-            }
-        };
+        let outcome = resolve_layer(
+            &layer,
+            service,
+            request,
+            catalog,
+            field_types,
+            &matched_vids,
+            trace.is_some(),
+        );
+
+        if let Some(trace) = trace.as_deref_mut() {
+            trace.push(LayerTrace::new(&layer, &outcome));
+        }
 
-        let salt = layer.get_salt();
-        let bucket = hash_to_bucket(hash_key_value, &salt);
+        if let LayerOutcome::Matched { vid, params, .. } = outcome {
+            merge_params_prioritized(&mut final_params, params)?;
+            matched_vids.push(vid);
+            matched_layers.push(layer.layer_id.clone());
+        }
+    }
 
-        let Some(vid) = layer.get_vid(bucket) else {
-            continue;
-        };
+    Ok(ServiceResult {
+        parameters: Value::Object(final_params),
+        vids: matched_vids,
+        matched_layers,
+    })
+}
 
-        let Some((eid, variant_service, rule_opt, params)) = catalog.get_variant(vid) else {
+/// Resolve one layer for a service: bucket, override, catalog, service,
+/// prerequisite and rule checks.
+///
+/// Prerequisites are checked against `matched_vids`, the variants already
+/// matched by higher-priority layers. With `explain`, the rule nodes that
+/// decided the outcome are recorded too.
+fn resolve_layer<'a>(
+    layer: &Layer,
+    service: &str,
+    request: &ExperimentRequest,
+    catalog: &'a ExperimentCatalog,
+    field_types: &HashMap<String, FieldType>,
+    matched_vids: &[i64],
+    explain: bool,
+) -> LayerOutcome<'a> {
+    let hash_key_value = match request.context.get(&layer.hash_key) {
+        Some(Value::String(s)) => s.as_str(),
+        Some(Value::Number(n)) => {
             tracing::warn!(
-                "Missing vid {} in catalog (layer: {}, bucket: {}), skipping",
-                vid,
-                layer.layer_id,
-                bucket
+                "Hash key '{}' is a number, converting to string for layer '{}'",
+                layer.hash_key,
+                layer.layer_id
             );
-            continue;
-        };
-
-        if variant_service != service {
-            continue;
+            &n.to_string()
+        }
+        Some(_) => {
+            tracing::warn!(
+                "Hash key '{}' must be a string or number for layer '{}', skipping",
+                layer.hash_key,
+                layer.layer_id
+            );
+            return LayerOutcome::NoHashKey;
         }
+        None => {
+            tracing::warn!(
+                "Hash key '{}' not found in context for layer '{}', skipping",
+                layer.hash_key,
+                layer.layer_id
+            );
+            return LayerOutcome::NoHashKey;
+        }
+    };
 
-        if let Some(rule) = rule_opt {
-            let rule_passed = match rule.evaluate(&request.context, field_types) {
-                Ok(passed) => passed,
-                Err(e) => {
-                    tracing::warn!(
-                        "Rule evaluation failed for eid {} (layer {}, vid {}): {}",
-                        eid,
-                        layer.layer_id,
-                        vid,
-                        e
-                    );
-                    false
-                }
-            };
+    let salt = layer.get_salt();
+    let bucket = hash_to_bucket(hash_key_value, &salt);
 
-            if !rule_passed {
-                continue;
-            }
+    // Forced assignment wins over bucketing when its vid lives in this layer
+    let forced_vid = catalog
+        .get_overrides(hash_key_value)
+        .iter()
+        .copied()
+        .find(|vid| layer.ranges.iter().any(|r| r.vid == *vid));
+
+    let Some(vid) = forced_vid.or_else(|| layer.get_vid(bucket)) else {
+        return LayerOutcome::Unassigned { bucket };
+    };
+
+    let Some((eid, variant_service, rule_opt, params)) = catalog.get_variant(vid) else {
+        tracing::warn!(
+            "Missing vid {} in catalog (layer: {}, bucket: {}), skipping",
+            vid,
+            layer.layer_id,
+            bucket
+        );
+        return LayerOutcome::UnknownVid { bucket, vid };
+    };
+
+    if variant_service != service {
+        return LayerOutcome::ServiceMismatch { bucket, vid, eid };
+    }
+
+    // Forced assignments bypass prerequisites, like the rule
+    if forced_vid.is_none() {
+        let prerequisites = catalog.get_experiment(eid).map_or(&[][..], |exp| &exp.prerequisites);
+        if let Some(unmet) = prerequisites
+            .iter()
+            .find(|p| !p.vids.iter().any(|vid| matched_vids.contains(vid)))
+        {
+            return LayerOutcome::PrerequisiteUnmet { bucket, vid, eid, prerequisite: unmet.eid };
         }
+    }
 
-        merge_params_prioritized(&mut final_params, params)?;
-        matched_vids.push(vid);
-        matched_layers.push(layer.layer_id.clone());
+    let mut branches = Vec::new();
+    if let Some(rule) = rule_opt.filter(|_| forced_vid.is_none()) {
+        let segments = catalog.segments();
+        let evaluated = if explain {
+            rule.explain_with(&request.context, field_types, segments)
+        } else {
+            rule.evaluate_with(&request.context, field_types, segments)
+                .map(|result| (result, Vec::new()))
+        };
+        match evaluated {
+            Ok((true, paths)) => branches = paths,
+            Ok((false, paths)) => {
+                return LayerOutcome::RuleRejected { bucket, vid, eid, error: None, branches: paths };
+            }
+            Err(e) => {
+                tracing::warn!(
+                    "Rule evaluation failed for eid {} (layer {}, vid {}): {}",
+                    eid,
+                    layer.layer_id,
+                    vid,
+                    e
+                );
+                return LayerOutcome::RuleRejected {
+                    bucket,
+                    vid,
+                    eid,
+                    error: Some(e.to_string()),
+                    branches: Vec::new(),
+                };
+            }
+        }
     }
 
-    Ok(ServiceResult {
-        parameters: Value::Object(final_params),
-        vids: matched_vids,
-        matched_layers,
-    })
+    LayerOutcome::Matched {
+        bucket,
+        vid,
+        eid,
+        forced: forced_vid.is_some(),
+        params,
+        branches,
+    }
 }
 
 /// Merge parameters with priority (higher priority layer wins for same keys)
@@ -240,6 +451,8 @@ mod tests {
                     params: json!({"feature_b": true, "timeout": 200}),
                 },
             ],
+            overrides: HashMap::new(),
+            prerequisites: vec![],
         };
         std::fs::write(
             experiments_dir.join("100.json"),
@@ -1,5 +1,5 @@
 use lazy_static::lazy_static;
-use prometheus::{Counter, Histogram, IntCounter, Registry};
+use prometheus::{Counter, Histogram, HistogramVec, IntCounter, Registry};
 
 lazy_static! {
     pub static ref REGISTRY: Registry = Registry::new();
@@ -23,6 +23,16 @@ lazy_static! {
         .buckets(vec![0.001, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1.0])
     ).unwrap();
     
+    // Per-route HTTP metrics (latency percentiles and error rates for SLOs)
+    pub static ref HTTP_REQUEST_DURATION: HistogramVec = HistogramVec::new(
+        prometheus::HistogramOpts::new(
+            "experiment_http_request_duration_seconds",
+            "HTTP request duration in seconds by route, method and status"
+        )
+        .buckets(vec![0.0005, 0.001, 0.0025, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1.0]),
+        &["route", "method", "status"]
+    ).unwrap();
+    
     // Layer metrics
     pub static ref LAYER_RELOAD_TOTAL: IntCounter = IntCounter::new(
         "experiment_layer_reload_total",
@@ -38,13 +48,27 @@ lazy_static! {
         "experiment_active_layers",
         "Number of active layers"
     ).unwrap();
+    
+    pub static ref LOADED_LAYERS: prometheus::IntGauge = prometheus::IntGauge::new(
+        "experiment_loaded_layers",
+        "Number of loaded layers, including disabled ones"
+    ).unwrap();
+    
+    // Catalog metrics
+    pub static ref CATALOG_EXPERIMENTS: prometheus::IntGauge = prometheus::IntGauge::new(
+        "experiment_catalog_experiments",
+        "Number of experiments in the catalog"
+    ).unwrap();
 }
 
 pub fn init() {
     REGISTRY.register(Box::new(REQUEST_TOTAL.clone())).unwrap();
     REGISTRY.register(Box::new(REQUEST_ERRORS.clone())).unwrap();
     REGISTRY.register(Box::new(REQUEST_DURATION.clone())).unwrap();
+    REGISTRY.register(Box::new(HTTP_REQUEST_DURATION.clone())).unwrap();
     REGISTRY.register(Box::new(LAYER_RELOAD_TOTAL.clone())).unwrap();
     REGISTRY.register(Box::new(LAYER_RELOAD_ERRORS.clone())).unwrap();
     REGISTRY.register(Box::new(ACTIVE_LAYERS.clone())).unwrap();
+    REGISTRY.register(Box::new(LOADED_LAYERS.clone())).unwrap();
+    REGISTRY.register(Box::new(CATALOG_EXPERIMENTS.clone())).unwrap();
 }
@@ -1,6 +1,9 @@
 use crate::error::{ExperimentError, Result};
+use crate::segment::{Segment, SegmentSet};
+use regex::Regex;
 use serde::{Deserialize, Serialize};
 use std::collections::HashMap;
+use std::sync::OnceLock;
 
 /// Field type information from control plane
 #[derive(Debug, Clone, Serialize, Deserialize, PartialEq)]
@@ -11,6 +14,8 @@ pub enum FieldType {
     Float,
     Bool,
     SemVer,
+    /// RFC 3339 string or unix seconds
+    Timestamp,
 }
 
 /// Operator for rule evaluation
@@ -25,6 +30,9 @@ pub enum Op {
     Lt,
     Lte,
     
+    /// Inclusive range: values = [lower, upper]
+    Between,
+    
     // Set operators
     In,
     NotIn,
@@ -32,6 +40,14 @@ pub enum Op {
     // String operators
     Like,
     NotLike,
+    Regex,
+    
+    // Time operators (timestamp fields only)
+    Before,
+    After,
+    
+    /// Unit is a member of any of the named segments: values = [segment names]
+    InSegment,
     
     // Boolean operators
     And,
@@ -59,13 +75,41 @@ pub enum Node {
         field: String,
         op: Op,
         values: Vec<serde_json::Value>,
+        /// Compiled `regex` pattern, not part of the config
+        #[serde(skip)]
+        regex: LazyRegex,
     },
 }
 
+/// Compiled pattern of a `regex` node, kept with the node so evaluation takes
+/// no lock. Filled by `check_structure` at load, or on first evaluation.
+#[derive(Debug, Clone, Default)]
+pub struct LazyRegex(OnceLock<Regex>);
+
+impl LazyRegex {
+    fn get_or_compile(&self, pattern: &str) -> Result<&Regex> {
+        if let Some(re) = self.0.get() {
+            return Ok(re);
+        }
+        
+        let re = Regex::new(pattern).map_err(|e| ExperimentError::InvalidRule(
+            format!("Invalid regex '{}': {}", pattern, e)
+        ))?;
+        Ok(self.0.get_or_init(|| re))
+    }
+}
+
 impl Node {
     /// Validate node structure against field type map
-    #[allow(dead_code)]
     pub fn validate(&self, field_types: &HashMap<String, FieldType>) -> Result<()> {
+        self.check_structure()?;
+        self.validate_types(field_types)
+    }
+    
+    /// Validate what does not depend on field types: arity, regex patterns,
+    /// timestamp literals and `between` bounds. Run at catalog load, before
+    /// the field type map is known.
+    pub fn check_structure(&self) -> Result<()> {
         match self {
             Node::And { children } => {
                 if children.is_empty() {
@@ -74,7 +118,7 @@ impl Node {
                     ));
                 }
                 for child in children {
-                    child.validate(field_types)?;
+                    child.check_structure()?;
                 }
             }
             Node::Or { children } => {
@@ -84,20 +128,13 @@ impl Node {
                     ));
                 }
                 for child in children {
-                    child.validate(field_types)?;
+                    child.check_structure()?;
                 }
             }
             Node::Not { child } => {
-                child.validate(field_types)?;
+                child.check_structure()?;
             }
-            Node::Field { field, op, values } => {
-                // Check field exists
-                let field_type = field_types
-                    .get(field)
-                    .ok_or_else(|| ExperimentError::InvalidRule(
-                        format!("Field '{}' not found in field type map", field)
-                    ))?;
-                
+            Node::Field { field, op, values, regex } => {
                 // Check values not empty
                 if values.is_empty() {
                     return Err(ExperimentError::InvalidRule(
@@ -115,25 +152,125 @@ impl Node {
                     _ => {}
                 }
                 
-                // Validate value types match field type
-                for value in values {
-                    validate_value_type(value, field_type, field)?;
+                validate_op_shape(field, op, values, regex)?;
+            }
+        }
+        Ok(())
+    }
+    
+    fn validate_types(&self, field_types: &HashMap<String, FieldType>) -> Result<()> {
+        match self {
+            Node::And { children } | Node::Or { children } => {
+                for child in children {
+                    child.validate_types(field_types)?;
                 }
             }
+            Node::Not { child } => {
+                child.validate_types(field_types)?;
+            }
+            Node::Field { field, op, values, .. } => {
+                // Check field exists
+                let field_type = field_types
+                    .get(field)
+                    .ok_or_else(|| ExperimentError::InvalidRule(
+                        format!("Field '{}' not found in field type map", field)
+                    ))?;
+                
+                // Validate value types match field type (in_segment values are segment names)
+                if *op != Op::InSegment {
+                    for value in values {
+                        validate_value_type(value, field_type, field)?;
+                    }
+                }
+                
+                validate_typed_op(field, op, values, field_type)?;
+            }
         }
         Ok(())
     }
     
-    /// Evaluate node against context
+    /// Segment names referenced by `in_segment` anywhere in this tree
+    pub fn segment_refs(&self) -> Vec<&str> {
+        let mut refs = Vec::new();
+        self.collect_segment_refs(&mut refs);
+        refs
+    }
+
+    fn collect_segment_refs<'a>(&'a self, refs: &mut Vec<&'a str>) {
+        match self {
+            Node::And { children } | Node::Or { children } => {
+                for child in children {
+                    child.collect_segment_refs(refs);
+                }
+            }
+            Node::Not { child } => child.collect_segment_refs(refs),
+            Node::Field { op: Op::InSegment, values, .. } => {
+                refs.extend(values.iter().filter_map(|v| v.as_str()));
+            }
+            Node::Field { .. } => {}
+        }
+    }
+    
+    /// Evaluate node against context (without segments; `in_segment` fails)
     pub fn evaluate(
         &self,
         ctx: &HashMap<String, serde_json::Value>,
         field_types: &HashMap<String, FieldType>,
+    ) -> Result<bool> {
+        self.evaluate_with(ctx, field_types, &SegmentSet::default())
+    }
+    
+    /// Evaluate like `evaluate_with`, also returning the paths of the nodes that
+    /// decided the result, e.g. `and[1].field(country eq)`.
+    ///
+    /// A passing `and` / failing `or` is decided by all of its children; a
+    /// failing `and` / passing `or` by the child that short-circuited it.
+    pub fn explain_with(
+        &self,
+        ctx: &HashMap<String, serde_json::Value>,
+        field_types: &HashMap<String, FieldType>,
+        segments: &SegmentSet,
+    ) -> Result<(bool, Vec<String>)> {
+        match self {
+            Node::And { children } | Node::Or { children } => {
+                let (kind, short_circuit) = match self {
+                    Node::And { .. } => ("and", false),
+                    _ => ("or", true),
+                };
+                let mut paths = Vec::new();
+                for (i, child) in children.iter().enumerate() {
+                    let (result, child_paths) = child.explain_with(ctx, field_types, segments)?;
+                    let child_paths = child_paths.into_iter().map(|p| format!("{}[{}].{}", kind, i, p));
+                    if result == short_circuit {
+                        return Ok((result, child_paths.collect()));
+                    }
+                    paths.extend(child_paths);
+                }
+                Ok((!short_circuit, paths))
+            }
+            Node::Not { child } => {
+                let (result, paths) = child.explain_with(ctx, field_types, segments)?;
+                Ok((!result, paths.into_iter().map(|p| format!("not.{}", p)).collect()))
+            }
+            Node::Field { field, op, .. } => {
+                let result = self.evaluate_with(ctx, field_types, segments)?;
+                let op = serde_json::to_value(op)?;
+                Ok((result, vec![format!("field({} {})", field, op.as_str().unwrap_or_default())]))
+            }
+        }
+    }
+    
+    /// Evaluate node against context, resolving `in_segment` against `segments`
+    pub fn evaluate_with(
+        &self,
+        ctx: &HashMap<String, serde_json::Value>,
+        field_types: &HashMap<String, FieldType>,
+        segments: &SegmentSet,
     ) -> Result<bool> {
         match self {
             Node::And { children } => {
                 for child in children {
-                    if !child.evaluate(ctx, field_types)? {
+                    if !child.evaluate_with(ctx, field_types, segments)? {
                         return Ok(false);
                     }
                 }
@@ -141,17 +278,17 @@ impl Node {
             }
             Node::Or { children } => {
                 for child in children {
-                    if child.evaluate(ctx, field_types)? {
+                    if child.evaluate_with(ctx, field_types, segments)? {
                         return Ok(true);
                     }
                 }
                 Ok(false)
             }
             Node::Not { child } => {
-                let result = child.evaluate(ctx, field_types)?;
+                let result = child.evaluate_with(ctx, field_types, segments)?;
                 Ok(!result)
             }
-            Node::Field { field, op, values } => {
+            Node::Field { field, op, values, regex } => {
                 // Get field value from context
                 let field_value = ctx
                     .get(field)
@@ -159,6 +296,11 @@ impl Node {
                         format!("Field '{}' not found in context", field)
                     ))?;
                 
+                if *op == Op::InSegment {
+                    return evaluate_in_segment(field, field_value, values, ctx, field_types, segments);
+                }
+                
+                
                 // Get field type
                 let field_type = field_types
                     .get(field)
@@ -167,14 +309,79 @@ impl Node {
                     ))?;
                 
                 // Evaluate based on operator
-                evaluate_field_op(field_value, op, values, field_type)
+                evaluate_field_op(field_value, op, values, field_type, regex)
             }
         }
     }
 }
 
+/// Validate operator arity and literals that can be checked without a field
+/// type; a `regex` pattern is compiled into `regex`
+fn validate_op_shape(
+    field_name: &str,
+    op: &Op,
+    values: &[serde_json::Value],
+    regex: &LazyRegex,
+) -> Result<()> {
+    match op {
+        Op::Eq | Op::Neq | Op::Gt | Op::Gte | Op::Lt | Op::Lte
+        | Op::Like | Op::NotLike | Op::Regex | Op::Before | Op::After => {
+            if values.len() != 1 {
+                return Err(ExperimentError::InvalidRule(
+                    format!("Field '{}' {:?} requires exactly one value", field_name, op)
+                ));
+            }
+        }
+        Op::Between => {
+            if values.len() != 2 {
+                return Err(ExperimentError::InvalidRule(
+                    format!("Field '{}' Between requires exactly two values", field_name)
+                ));
+            }
+            // Bounds whose order is known without a field type: numbers, then timestamps
+            let inverted = match (values[0].as_f64(), values[1].as_f64()) {
+                (Some(lower), Some(upper)) => lower > upper,
+                _ => match (parse_timestamp(&values[0]), parse_timestamp(&values[1])) {
+                    (Some(lower), Some(upper)) => lower > upper,
+                    _ => false,
+                },
+            };
+            if inverted {
+                return Err(ExperimentError::InvalidRule(
+                    format!("Field '{}' Between lower bound is greater than upper bound", field_name)
+                ));
+            }
+        }
+        _ => {}
+    }
+    
+    match op {
+        Op::Regex => {
+            let pattern = values[0].as_str().ok_or_else(|| ExperimentError::InvalidRule(
+                format!("Field '{}' Regex pattern must be a string", field_name)
+            ))?;
+            regex.get_or_compile(pattern)?;
+        }
+        Op::Before | Op::After => {
+            if parse_timestamp(&values[0]).is_none() {
+                return Err(ExperimentError::InvalidRule(
+                    format!("Field '{}' {:?} value {} is not a valid timestamp", field_name, op, values[0])
+                ));
+            }
+        }
+        Op::InSegment => {
+            if values.iter().any(|v| !v.is_string()) {
+                return Err(ExperimentError::InvalidRule(
+                    format!("Field '{}' InSegment values must be segment names", field_name)
+                ));
+            }
+        }
+        _ => {}
+    }
+    Ok(())
+}
+
 /// Validate that a value matches the expected field type
-#[allow(dead_code)]
 fn validate_value_type(value: &serde_json::Value, field_type: &FieldType, field_name: &str) -> Result<()> {
     use serde_json::Value;
     
@@ -183,6 +390,7 @@ fn validate_value_type(value: &serde_json::Value, field_type: &FieldType, field_
         (FieldType::Int, Value::Number(n)) if n.is_i64() => Ok(()),
         (FieldType::Float, Value::Number(_)) => Ok(()),
         (FieldType::Bool, Value::Bool(_)) => Ok(()),
+        (FieldType::Timestamp, v) if parse_timestamp(v).is_some() => Ok(()),
         (FieldType::SemVer, Value::String(s)) => {
             // Basic semver validation
             if s.split('.').count() >= 2 {
@@ -199,12 +407,115 @@ fn validate_value_type(value: &serde_json::Value, field_type: &FieldType, field_
     }
 }
 
+/// Validate operators that only apply to certain field types or value shapes
+fn validate_typed_op(field_name: &str, op: &Op, values: &[serde_json::Value], field_type: &FieldType) -> Result<()> {
+    match op {
+        Op::Between => {
+            if !matches!(field_type, FieldType::Int | FieldType::Float | FieldType::SemVer | FieldType::Timestamp) {
+                return Err(ExperimentError::InvalidRule(
+                    format!("Field '{}' of type {:?} does not support Between", field_name, field_type)
+                ));
+            }
+            if values.len() != 2 {
+                return Err(ExperimentError::InvalidRule(
+                    format!("Field '{}' Between requires exactly two values", field_name)
+                ));
+            }
+            if compare_values(&values[0], &values[1], field_type)? == std::cmp::Ordering::Greater {
+                return Err(ExperimentError::InvalidRule(
+                    format!("Field '{}' Between lower bound is greater than upper bound", field_name)
+                ));
+            }
+        }
+        Op::Regex => {
+            if *field_type != FieldType::String {
+                return Err(ExperimentError::InvalidRule(
+                    format!("Field '{}' of type {:?} does not support Regex", field_name, field_type)
+                ));
+            }
+            if values.len() != 1 {
+                return Err(ExperimentError::InvalidRule(
+                    format!("Field '{}' Regex requires exactly one value", field_name)
+                ));
+            }
+        }
+        Op::Before | Op::After => {
+            if *field_type != FieldType::Timestamp {
+                return Err(ExperimentError::InvalidRule(
+                    format!("Field '{}' of type {:?} does not support {:?}", field_name, field_type, op)
+                ));
+            }
+            if values.len() != 1 {
+                return Err(ExperimentError::InvalidRule(
+                    format!("Field '{}' {:?} requires exactly one value", field_name, op)
+                ));
+            }
+        }
+        Op::InSegment => {
+            if !matches!(field_type, FieldType::String | FieldType::Int) {
+                return Err(ExperimentError::InvalidRule(
+                    format!("Field '{}' of type {:?} does not support InSegment", field_name, field_type)
+                ));
+            }
+            if values.iter().any(|v| !v.is_string()) {
+                return Err(ExperimentError::InvalidRule(
+                    format!("Field '{}' InSegment values must be segment names", field_name)
+                ));
+            }
+        }
+        _ => {}
+    }
+    Ok(())
+}
+
+/// Evaluate `in_segment`: true if the unit belongs to any of the named segments
+fn evaluate_in_segment(
+    field_name: &str,
+    field_value: &serde_json::Value,
+    names: &[serde_json::Value],
+    ctx: &HashMap<String, serde_json::Value>,
+    field_types: &HashMap<String, FieldType>,
+    segments: &SegmentSet,
+) -> Result<bool> {
+    use serde_json::Value;
+    
+    let unit = match field_value {
+        Value::String(s) => std::borrow::Cow::Borrowed(s.as_str()),
+        Value::Number(n) => std::borrow::Cow::Owned(n.to_string()),
+        _ => {
+            return Err(ExperimentError::InvalidRule(
+                format!("InSegment field '{}' must be a string or number", field_name)
+            ));
+        }
+    };
+    
+    for name in names {
+        let name = name.as_str().ok_or_else(|| ExperimentError::InvalidRule(
+            "InSegment values must be segment names".to_string()
+        ))?;
+        let matched = match segments.get(name) {
+            Some(Segment::Ids(ids)) => ids.contains(unit.as_ref()),
+            Some(Segment::Rule(rule)) => rule.evaluate_with(ctx, field_types, segments)?,
+            None => {
+                return Err(ExperimentError::InvalidRule(
+                    format!("Unknown segment '{}'", name)
+                ));
+            }
+        };
+        if matched {
+            return Ok(true);
+        }
+    }
+    Ok(false)
+}
+
 /// Evaluate field operation
 fn evaluate_field_op(
     field_value: &serde_json::Value,
     op: &Op,
     values: &[serde_json::Value],
     field_type: &FieldType,
+    regex: &LazyRegex,
 ) -> Result<bool> {
     use serde_json::Value;
     
@@ -259,6 +570,16 @@ fn evaluate_field_op(
             let cmp = compare_values(field_value, &values[0], field_type)?;
             Ok(cmp == std::cmp::Ordering::Less || cmp == std::cmp::Ordering::Equal)
         }
+        Op::Between => {
+            if values.len() != 2 {
+                return Err(ExperimentError::InvalidRule(
+                    "Between operator requires exactly two values".to_string()
+                ));
+            }
+            let lower = compare_values(field_value, &values[0], field_type)?;
+            let upper = compare_values(field_value, &values[1], field_type)?;
+            Ok(lower != std::cmp::Ordering::Less && upper != std::cmp::Ordering::Greater)
+        }
         Op::In => {
             for value in values {
                 if compare_values(field_value, value, field_type)? == std::cmp::Ordering::Equal {
@@ -306,6 +627,43 @@ fn evaluate_field_op(
                 )),
             }
         }
+        Op::Regex => {
+            if values.len() != 1 {
+                return Err(ExperimentError::InvalidRule(
+                    "Regex operator requires exactly one value".to_string()
+                ));
+            }
+            match (field_value, &values[0]) {
+                (Value::String(field_str), Value::String(pattern)) => {
+                    Ok(regex.get_or_compile(pattern)?.is_match(field_str))
+                }
+                _ => Err(ExperimentError::InvalidRule(
+                    "Regex operator requires string values".to_string()
+                )),
+            }
+        }
+        Op::Before | Op::After => {
+            if values.len() != 1 {
+                return Err(ExperimentError::InvalidRule(
+                    format!("{:?} operator requires exactly one value", op)
+                ));
+            }
+            if *field_type != FieldType::Timestamp {
+                return Err(ExperimentError::InvalidRule(
+                    format!("{:?} operator requires a timestamp field", op)
+                ));
+            }
+            let cmp = compare_values(field_value, &values[0], field_type)?;
+            match op {
+                Op::Before => Ok(cmp == std::cmp::Ordering::Less),
+                _ => Ok(cmp == std::cmp::Ordering::Greater),
+            }
+        }
+        Op::InSegment => {
+            Err(ExperimentError::InvalidRule(
+                "InSegment operator requires segment context".to_string()
+            ))
+        }
         Op::And | Op::Or | Op::Not => {
             Err(ExperimentError::InvalidRule(
                 format!("Boolean operator {:?} cannot be used in field comparison", op)
@@ -371,6 +729,27 @@ fn compare_values(
                 )),
             }
         }
+        FieldType::Timestamp => {
+            match (parse_timestamp(left), parse_timestamp(right)) {
+                (Some(l), Some(r)) => Ok(l.cmp(&r)),
+                _ => Err(ExperimentError::InvalidRule(
+                    "Timestamp comparison requires RFC 3339 strings or unix seconds".to_string()
+                )),
+            }
+        }
+    }
+}
+
+/// Parse a timestamp value (RFC 3339 string or unix seconds) into unix seconds
+fn parse_timestamp(value: &serde_json::Value) -> Option<i64> {
+    use serde_json::Value;
+    
+    match value {
+        Value::Number(n) => n.as_i64(),
+        Value::String(s) => chrono::DateTime::parse_from_rfc3339(s)
+            .ok()
+            .map(|dt| dt.timestamp()),
+        _ => None,
     }
 }
 
@@ -438,6 +817,7 @@ mod tests {
             ("balance".to_string(), FieldType::Float),
             ("premium".to_string(), FieldType::Bool),
             ("app_version".to_string(), FieldType::SemVer),
+            ("signup_time".to_string(), FieldType::Timestamp),
         ]
         .into_iter()
         .collect()
@@ -453,11 +833,13 @@ mod tests {
                     field: "country".to_string(),
                     op: Op::Eq,
                     values: vec![json!("US")],
+                    regex: Default::default(),
                 },
                 Node::Field {
                     field: "age".to_string(),
                     op: Op::Gte,
                     values: vec![json!(18)],
+                    regex: Default::default(),
                 },
             ],
         };
@@ -473,6 +855,7 @@ mod tests {
             field: "unknown_field".to_string(),
             op: Op::Eq,
             values: vec![json!("value")],
+            regex: Default::default(),
         };
         
         assert!(node.validate(&field_types).is_err());
@@ -486,6 +869,7 @@ mod tests {
             field: "country".to_string(),
             op: Op::Eq,
             values: vec![],
+            regex: Default::default(),
         };
         
         assert!(node.validate(&field_types).is_err());
@@ -499,6 +883,7 @@ mod tests {
             field: "age".to_string(),
             op: Op::Eq,
             values: vec![json!("not_a_number")],
+            regex: Default::default(),
         };
         
         assert!(node.validate(&field_types).is_err());
@@ -517,6 +902,7 @@ mod tests {
             field: "country".to_string(),
             op: Op::Eq,
             values: vec![json!("US")],
+            regex: Default::default(),
         };
         
         assert_eq!(node.evaluate(&ctx, &field_types).unwrap(), true);
@@ -535,6 +921,7 @@ mod tests {
             field: "country".to_string(),
             op: Op::Neq,
             values: vec![json!("US")],
+            regex: Default::default(),
         };
         
         assert_eq!(node.evaluate(&ctx, &field_types).unwrap(), true);
@@ -553,6 +940,7 @@ mod tests {
             field: "age".to_string(),
             op: Op::Gte,
             values: vec![json!(18)],
+            regex: Default::default(),
         };
         
         assert_eq!(node.evaluate(&ctx, &field_types).unwrap(), true);
@@ -571,6 +959,7 @@ mod tests {
             field: "country".to_string(),
             op: Op::In,
             values: vec![json!("US"), json!("CA"), json!("UK")],
+            regex: Default::default(),
         };
         
         assert_eq!(node.evaluate(&ctx, &field_types).unwrap(), true);
@@ -589,6 +978,7 @@ mod tests {
             field: "country".to_string(),
             op: Op::NotIn,
             values: vec![json!("US"), json!("CA"), json!("UK")],
+            regex: Default::default(),
         };
         
         assert_eq!(node.evaluate(&ctx, &field_types).unwrap(), true);
@@ -607,6 +997,7 @@ mod tests {
             field: "user_id".to_string(),
             op: Op::Like,
             values: vec![json!("user_*")],
+            regex: Default::default(),
         };
         
         assert_eq!(node.evaluate(&ctx, &field_types).unwrap(), true);
@@ -628,11 +1019,13 @@ mod tests {
                     field: "country".to_string(),
                     op: Op::Eq,
                     values: vec![json!("US")],
+                    regex: Default::default(),
                 },
                 Node::Field {
                     field: "age".to_string(),
                     op: Op::Gte,
                     values: vec![json!(18)],
+                    regex: Default::default(),
                 },
             ],
         };
@@ -656,11 +1049,13 @@ mod tests {
                     field: "country".to_string(),
                     op: Op::Eq,
                     values: vec![json!("US")],
+                    regex: Default::default(),
                 },
                 Node::Field {
                     field: "age".to_string(),
                     op: Op::Gte,
                     values: vec![json!(18)],
+                    regex: Default::default(),
                 },
             ],
         };
@@ -682,6 +1077,7 @@ mod tests {
                 field: "country".to_string(),
                 op: Op::Eq,
                 values: vec![json!("US")],
+                regex: Default::default(),
             }),
         };
         
@@ -708,11 +1104,13 @@ mod tests {
                             field: "country".to_string(),
                             op: Op::Eq,
                             values: vec![json!("US")],
+                            regex: Default::default(),
                         },
                         Node::Field {
                             field: "age".to_string(),
                             op: Op::Gte,
                             values: vec![json!(18)],
+                            regex: Default::default(),
                         },
                     ],
                 },
@@ -720,6 +1118,7 @@ mod tests {
                     field: "premium".to_string(),
                     op: Op::Eq,
                     values: vec![json!(true)],
+                    regex: Default::default(),
                 },
             ],
         };
@@ -727,6 +1126,250 @@ mod tests {
         assert_eq!(node.evaluate(&ctx, &field_types).unwrap(), true);
     }
     
+    #[test]
+    fn test_evaluate_between() {
+        let field_types = setup_field_types();
+        let node = Node::Field {
+            field: "age".to_string(),
+            op: Op::Between,
+            values: vec![json!(18), json!(30)],
+            regex: Default::default(),
+        };
+        
+        for (age, expected) in [(17, false), (18, true), (25, true), (30, true), (31, false)] {
+            let ctx = [("age".to_string(), json!(age))].into_iter().collect();
+            assert_eq!(node.evaluate(&ctx, &field_types).unwrap(), expected, "age {}", age);
+        }
+    }
+    
+    #[test]
+    fn test_evaluate_semver_between() {
+        let field_types = setup_field_types();
+        let ctx = [("app_version".to_string(), json!("2.3.1"))].into_iter().collect();
+        
+        let node = Node::Field {
+            field: "app_version".to_string(),
+            op: Op::Between,
+            values: vec![json!("2.0.0"), json!("2.10.0")],
+            regex: Default::default(),
+        };
+        
+        assert_eq!(node.evaluate(&ctx, &field_types).unwrap(), true);
+    }
+    
+    #[test]
+    fn test_evaluate_regex() {
+        let field_types = setup_field_types();
+        let node = Node::Field {
+            field: "user_id".to_string(),
+            op: Op::Regex,
+            values: vec![json!("^user_[0-9]+$")],
+            regex: Default::default(),
+        };
+        
+        let ctx = [("user_id".to_string(), json!("user_12345"))].into_iter().collect();
+        assert_eq!(node.evaluate(&ctx, &field_types).unwrap(), true);
+        
+        let ctx = [("user_id".to_string(), json!("admin_1"))].into_iter().collect();
+        assert_eq!(node.evaluate(&ctx, &field_types).unwrap(), false);
+    }
+    
+    #[test]
+    fn test_evaluate_before_after() {
+        let field_types = setup_field_types();
+        let ctx = [("signup_time".to_string(), json!("2024-06-01T12:00:00Z"))]
+            .into_iter()
+            .collect();
+        
+        let before = Node::Field {
+            field: "signup_time".to_string(),
+            op: Op::Before,
+            values: vec![json!("2025-01-01T00:00:00+08:00")],
+            regex: Default::default(),
+        };
+        assert_eq!(before.evaluate(&ctx, &field_types).unwrap(), true);
+        
+        // 2024-01-01T00:00:00Z as unix seconds
+        let after = Node::Field {
+            field: "signup_time".to_string(),
+            op: Op::After,
+            values: vec![json!(1704067200)],
+            regex: Default::default(),
+        };
+        assert_eq!(after.evaluate(&ctx, &field_types).unwrap(), true);
+        
+        let ctx = [("signup_time".to_string(), json!(1704067200))].into_iter().collect();
+        assert_eq!(after.evaluate(&ctx, &field_types).unwrap(), false);
+    }
+    
+    #[test]
+    fn test_typed_op_validation() {
+        let field_types = setup_field_types();
+        
+        // Inverted bounds
+        let node = Node::Field {
+            field: "age".to_string(),
+            op: Op::Between,
+            values: vec![json!(30), json!(18)],
+            regex: Default::default(),
+        };
+        assert!(node.validate(&field_types).is_err());
+        
+        // Invalid pattern
+        let node = Node::Field {
+            field: "country".to_string(),
+            op: Op::Regex,
+            values: vec![json!("(unclosed")],
+            regex: Default::default(),
+        };
+        assert!(node.validate(&field_types).is_err());
+        
+        // Time operator on a non-timestamp field
+        let node = Node::Field {
+            field: "age".to_string(),
+            op: Op::Before,
+            values: vec![json!(18)],
+            regex: Default::default(),
+        };
+        assert!(node.validate(&field_types).is_err());
+        
+        // Timestamp value must parse
+        let node = Node::Field {
+            field: "signup_time".to_string(),
+            op: Op::After,
+            values: vec![json!("yesterday")],
+            regex: Default::default(),
+        };
+        assert!(node.validate(&field_types).is_err());
+        
+        let node = Node::Field {
+            field: "signup_time".to_string(),
+            op: Op::After,
+            values: vec![json!("2024-01-01T00:00:00Z")],
+            regex: Default::default(),
+        };
+        assert!(node.validate(&field_types).is_ok());
+        
+        // Single-value operators reject extra values
+        let node = Node::Field {
+            field: "country".to_string(),
+            op: Op::Regex,
+            values: vec![json!("^U"), json!("^C")],
+            regex: Default::default(),
+        };
+        assert!(node.validate(&field_types).is_err());
+        
+        let node = Node::Field {
+            field: "signup_time".to_string(),
+            op: Op::Before,
+            values: vec![json!(1704067200), json!(1704153600)],
+            regex: Default::default(),
+        };
+        assert!(node.validate(&field_types).is_err());
+    }
+    
+    #[test]
+    fn test_explain() {
+        let field_types = setup_field_types();
+        let segments = SegmentSet::default();
+        let ctx: HashMap<String, serde_json::Value> = [
+            ("country".to_string(), json!("US")),
+            ("age".to_string(), json!(25)),
+        ]
+        .into_iter()
+        .collect();
+        
+        let node = Node::And {
+            children: vec![
+                Node::Field {
+                    field: "age".to_string(),
+                    op: Op::Gte,
+                    values: vec![json!(18)],
+                    regex: Default::default(),
+                },
+                Node::Or {
+                    children: vec![
+                        Node::Field {
+                            field: "country".to_string(),
+                            op: Op::Eq,
+                            values: vec![json!("CN")],
+                            regex: Default::default(),
+                        },
+                        Node::Not {
+                            child: Box::new(Node::Field {
+                                field: "country".to_string(),
+                                op: Op::In,
+                                values: vec![json!("JP"), json!("KR")],
+                                regex: Default::default(),
+                            }),
+                        },
+                    ],
+                },
+            ],
+        };
+        
+        // Passing and: every child decides; passing or: the child that matched
+        let (result, paths) = node.explain_with(&ctx, &field_types, &segments).unwrap();
+        assert!(result);
+        assert_eq!(paths, vec!["and[0].field(age gte)", "and[1].or[1].not.field(country in)"]);
+        
+        // Failing and: the child that failed
+        let ctx: HashMap<String, serde_json::Value> = [
+            ("country".to_string(), json!("US")),
+            ("age".to_string(), json!(16)),
+        ]
+        .into_iter()
+        .collect();
+        let (result, paths) = node.explain_with(&ctx, &field_types, &segments).unwrap();
+        assert!(!result);
+        assert_eq!(paths, vec!["and[0].field(age gte)"]);
+        assert_eq!(node.evaluate(&ctx, &field_types).unwrap(), result);
+    }
+    
+    #[test]
+    fn test_check_structure() {
+        let field = |field: &str, op: Op, values: Vec<serde_json::Value>| Node::Field {
+            field: field.to_string(),
+            op,
+            values,
+            regex: Default::default(),
+        };
+        
+        // No field type map needed
+        assert!(field("age", Op::Between, vec![json!(18), json!(30)]).check_structure().is_ok());
+        assert!(field("age", Op::Between, vec![json!(30), json!(18)]).check_structure().is_err());
+        assert!(field("age", Op::Between, vec![json!(18)]).check_structure().is_err());
+        assert!(field(
+            "signup_time",
+            Op::Between,
+            vec![json!("2024-02-01T00:00:00Z"), json!("2024-01-01T00:00:00Z")],
+        )
+        .check_structure()
+        .is_err());
+        assert!(field("country", Op::Eq, vec![json!("US"), json!("CN")]).check_structure().is_err());
+        assert!(field("country", Op::Regex, vec![json!("(unclosed")]).check_structure().is_err());
+        assert!(field("signup_time", Op::After, vec![json!("yesterday")]).check_structure().is_err());
+        assert!(field("user_id", Op::InSegment, vec![json!(1)]).check_structure().is_err());
+        
+        // Nested problems are found
+        let node = Node::And {
+            children: vec![
+                field("country", Op::Eq, vec![json!("US")]),
+                Node::Not {
+                    child: Box::new(field("country", Op::Regex, vec![json!("[")])),
+                },
+            ],
+        };
+        assert!(node.check_structure().is_err());
+        assert!(Node::Or { children: vec![] }.check_structure().is_err());
+        
+        // The pattern is compiled once at load and kept with the node
+        let node = field("country", Op::Regex, vec![json!("^U")]);
+        node.check_structure().unwrap();
+        let Node::Field { regex, .. } = &node else { unreachable!() };
+        assert!(regex.0.get().is_some());
+    }
+    
     #[test]
     fn test_compare_semver() {
         assert_eq!(compare_semver("1.2.3", "1.2.3").unwrap(), std::cmp::Ordering::Equal);
@@ -0,0 +1,270 @@
+use crate::error::{ExperimentError, Result};
+use crate::rule::Node;
+use serde::{Deserialize, Serialize};
+use std::collections::{HashMap, HashSet};
+use std::path::{Path, PathBuf};
+
+/// Segment definition loaded from `configs/segments`.
+///
+/// An audience defined once and referenced from experiment rules with the
+/// `in_segment` operator. Exactly one of `rule` / `ids` must be set.
+#[derive(Debug, Clone, Serialize, Deserialize)]
+pub struct SegmentDef {
+    /// Unique segment name, referenced by `in_segment`
+    pub name: String,
+
+    /// Rule-defined audience, evaluated against the request context
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub rule: Option<Node>,
+
+    /// Uploaded ID list, matched against the value of the `in_segment` field
+    #[serde(default, skip_serializing_if = "Vec::is_empty")]
+    pub ids: Vec<String>,
+}
+
+/// Resolved segment membership
+#[derive(Debug, Clone)]
+pub enum Segment {
+    Rule(Node),
+    Ids(HashSet<String>),
+}
+
+/// All segments, keyed by name
+#[derive(Debug, Clone, Default)]
+pub struct SegmentSet {
+    segments: HashMap<String, Segment>,
+}
+
+impl SegmentSet {
+    pub fn load_from_dir(dir: PathBuf) -> Result<Self> {
+        if !dir.exists() {
+            tracing::warn!("Segments directory does not exist: {:?}", dir);
+            return Ok(Self::default());
+        }
+
+        let mut defs = Vec::new();
+        for entry in std::fs::read_dir(&dir)? {
+            let path = entry?.path();
+
+            if !path.is_file() {
+                continue;
+            }
+
+            let Some(ext) = path.extension().and_then(|s| s.to_str()) else {
+                continue;
+            };
+
+            if ext != "json" && ext != "yaml" && ext != "yml" {
+                continue;
+            }
+
+            defs.push(Self::read_segment_file(&path)?);
+        }
+
+        Self::from_defs(defs)
+    }
+
+    /// Build from definitions, validating names, shape and references
+    pub fn from_defs(defs: Vec<SegmentDef>) -> Result<Self> {
+        let mut segments = HashMap::new();
+
+        for def in defs {
+            if def.name.is_empty() {
+                return Err(ExperimentError::InvalidRule(
+                    "Segment name must not be empty".to_string(),
+                ));
+            }
+
+            let segment = match (def.rule, def.ids.is_empty()) {
+                (Some(rule), true) => {
+                    rule.check_structure().map_err(|e| match e {
+                        ExperimentError::InvalidRule(msg) => ExperimentError::InvalidRule(
+                            format!("Segment '{}': {}", def.name, msg),
+                        ),
+                        other => other,
+                    })?;
+                    // Segments are flat: no segment may reference another
+                    if let Some(name) = rule.segment_refs().first() {
+                        return Err(ExperimentError::InvalidRule(format!(
+                            "Segment '{}' rule must not use in_segment (references '{}')",
+                            def.name, name
+                        )));
+                    }
+                    Segment::Rule(rule)
+                }
+                (None, false) => Segment::Ids(def.ids.into_iter().collect()),
+                _ => {
+                    return Err(ExperimentError::InvalidRule(format!(
+                        "Segment '{}' must define exactly one of rule or ids",
+                        def.name
+                    )));
+                }
+            };
+
+            if segments.insert(def.name.clone(), segment).is_some() {
+                return Err(ExperimentError::InvalidRule(format!(
+                    "Duplicate segment '{}'",
+                    def.name
+                )));
+            }
+        }
+
+        Ok(Self { segments })
+    }
+
+    fn read_segment_file(path: &Path) -> Result<SegmentDef> {
+        let content = std::fs::read_to_string(path)?;
+
+        // Try JSON first, then YAML
+        let def: SegmentDef = serde_json::from_str(&content)
+            .or_else(|_| serde_yaml::from_str(&content).map_err(ExperimentError::from))?;
+
+        Ok(def)
+    }
+
+    #[inline]
+    pub fn get(&self, name: &str) -> Option<&Segment> {
+        self.segments.get(name)
+    }
+
+    #[inline]
+    pub fn contains(&self, name: &str) -> bool {
+        self.segments.contains_key(name)
+    }
+
+    /// Segment names, sorted
+    pub fn names(&self) -> Vec<String> {
+        let mut names: Vec<String> = self.segments.keys().cloned().collect();
+        names.sort();
+        names
+    }
+
+    pub fn len(&self) -> usize {
+        self.segments.len()
+    }
+
+    #[allow(dead_code)]
+    pub fn is_empty(&self) -> bool {
+        self.segments.is_empty()
+    }
+
+    /// Rule-defined segments as (name, rule), sorted by name
+    pub fn rules(&self) -> Vec<(&str, &Node)> {
+        let mut rules: Vec<(&str, &Node)> = self
+            .segments
+            .iter()
+            .filter_map(|(name, segment)| match segment {
+                Segment::Rule(rule) => Some((name.as_str(), rule)),
+                Segment::Ids(_) => None,
+            })
+            .collect();
+        rules.sort_by_key(|(name, _)| *name);
+        rules
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use crate::rule::{FieldType, Op};
+    use serde_json::json;
+
+    fn ctx(user_id: &str, country: &str) -> HashMap<String, serde_json::Value> {
+        [
+            ("user_id".to_string(), json!(user_id)),
+            ("country".to_string(), json!(country)),
+        ]
+        .into_iter()
+        .collect()
+    }
+
+    #[test]
+    fn test_in_segment() {
+        let segments = SegmentSet::from_defs(vec![
+            SegmentDef {
+                name: "beta".to_string(),
+                rule: None,
+                ids: vec!["u1".to_string(), "u2".to_string()],
+            },
+            SegmentDef {
+                name: "us".to_string(),
+                rule: Some(Node::Field {
+                    field: "country".to_string(),
+                    op: Op::Eq,
+                    values: vec![json!("US")],
+                    regex: Default::default(),
+                }),
+                ids: vec![],
+            },
+        ])
+        .unwrap();
+
+        let field_types: HashMap<String, FieldType> = [
+            ("user_id".to_string(), FieldType::String),
+            ("country".to_string(), FieldType::String),
+        ]
+        .into_iter()
+        .collect();
+
+        let rule = Node::Field {
+            field: "user_id".to_string(),
+            op: Op::InSegment,
+            values: vec![json!("beta"), json!("us")],
+            regex: Default::default(),
+        };
+
+        // ID list hit, rule hit, neither
+        assert!(rule.evaluate_with(&ctx("u1", "CN"), &field_types, &segments).unwrap());
+        assert!(rule.evaluate_with(&ctx("u9", "US"), &field_types, &segments).unwrap());
+        assert!(!rule.evaluate_with(&ctx("u9", "CN"), &field_types, &segments).unwrap());
+
+        // Unknown segment is an evaluation error, not a silent miss
+        assert!(rule.evaluate(&ctx("u1", "CN"), &field_types).is_err());
+    }
+
+    #[test]
+    fn test_segment_defs_rejected() {
+        let both = SegmentDef {
+            name: "both".to_string(),
+            rule: Some(Node::Field {
+                field: "country".to_string(),
+                op: Op::Eq,
+                values: vec![json!("US")],
+                regex: Default::default(),
+            }),
+            ids: vec!["u1".to_string()],
+        };
+        assert!(SegmentSet::from_defs(vec![both]).is_err());
+
+        let nested = SegmentDef {
+            name: "nested".to_string(),
+            rule: Some(Node::Field {
+                field: "user_id".to_string(),
+                op: Op::InSegment,
+                values: vec![json!("beta")],
+                regex: Default::default(),
+            }),
+            ids: vec![],
+        };
+        assert!(SegmentSet::from_defs(vec![nested]).is_err());
+
+        let bad_regex = SegmentDef {
+            name: "bad_regex".to_string(),
+            rule: Some(Node::Field {
+                field: "country".to_string(),
+                op: Op::Regex,
+                values: vec![json!("(unclosed")],
+                regex: Default::default(),
+            }),
+            ids: vec![],
+        };
+        assert!(SegmentSet::from_defs(vec![bad_regex]).is_err());
+
+        let dup = || SegmentDef {
+            name: "dup".to_string(),
+            rule: None,
+            ids: vec!["u1".to_string()],
+        };
+        assert!(SegmentSet::from_defs(vec![dup(), dup()]).is_err());
+    }
+}
@@ -1,33 +1,54 @@
 use crate::catalog::ExperimentCatalog;
 use crate::config::Config;
-use crate::layer::LayerManager;
-use crate::merge::{merge_layers_batch, ExperimentRequest, ExperimentResponse};
+use crate::error::{ErrorKind, ExperimentError};
+use crate::health::{HealthRegistry, HealthStatus, LAYERS};
+use crate::layer::{LayerManager, BUCKET_SIZE};
+use crate::merge::{
+    evaluate_batch, merge_layers_batch, EvaluateResponse, ExperimentRequest, ExperimentResponse,
+};
 use crate::metrics;
 use crate::rule::FieldType;
 use axum::{
-    extract::{Path, State},
-    http::StatusCode,
+    extract::{MatchedPath, Path, Request, State},
+    http::{HeaderName, StatusCode},
+    middleware::{self, Next},
     response::{IntoResponse, Response},
-    routing::{get, post},
+    routing::{get, post, put},
     Json, Router,
 };
 use parking_lot::RwLock;
 use prometheus::{Encoder, TextEncoder};
-use std::collections::HashMap;
+use std::collections::{BTreeMap, HashMap};
 use std::sync::Arc;
-use tower_http::trace::TraceLayer;
+use std::time::Instant;
+use tower::ServiceBuilder;
+use tower_http::request_id::{MakeRequestUuid, PropagateRequestIdLayer, SetRequestIdLayer};
+use tower_http::trace::{DefaultOnResponse, TraceLayer};
+use tower_http::LatencyUnit;
+use tracing::Level;
+use tracing_subscriber::{reload, EnvFilter, Registry};
+
+/// Handle for swapping the global log filter at runtime
+pub type LogFilterHandle = reload::Handle<EnvFilter, Registry>;
+
+const REQUEST_ID_HEADER: &str = "x-request-id";
 
 #[derive(Clone)]
 struct AppState {
     layer_manager: Arc<LayerManager>,
     catalog: Arc<ExperimentCatalog>,
     field_types: Arc<RwLock<HashMap<String, FieldType>>>,
+    health: Arc<HealthRegistry>,
+    log_filter: LogFilterHandle,
 }
 
 pub async fn run_server(
     config: Config,
     layer_manager: Arc<LayerManager>,
     catalog: Arc<ExperimentCatalog>,
+    health: Arc<HealthRegistry>,
+    log_filter: LogFilterHandle,
+    shutdown: impl std::future::Future<Output = ()> + Send + 'static,
 ) -> anyhow::Result<()> {
     // Initialize metrics
     metrics::init();
@@ -36,31 +57,129 @@ pub async fn run_server(
         layer_manager,
         catalog,
         field_types: Arc::new(RwLock::new(HashMap::new())),
+        health,
+        log_filter,
     };
 
     // Build application router
     let app = Router::new()
         .route("/health", get(health_check))
+        .route("/livez", get(liveness))
+        .route("/readyz", get(readiness))
         .route("/experiment", post(experiment_handler))
+        .route("/evaluate", post(evaluate_handler))
         .route("/layers", get(list_layers))
         .route("/layers/:layer_id", get(get_layer))
+        .route("/layers/:layer_id/capacity", get(get_layer_capacity))
         .route("/layers/:layer_id/rollback", post(rollback_layer))
+        .route("/validate", get(validate_config))
+        .route("/debug/configz", get(configz))
         .route("/field_types", get(get_field_types))
         .route("/field_types", post(update_field_types))
         .route("/metrics", get(metrics_handler))
-        .layer(TraceLayer::new_for_http())
+        .route_layer(middleware::from_fn(track_route_metrics))
+        // Access logging with request IDs: an incoming X-Request-ID is kept,
+        // otherwise a UUID is generated. The ID is recorded on the request span,
+        // so every log line emitted while handling the request carries it, and
+        // is echoed back on the response (errors included).
+        .layer(
+            ServiceBuilder::new()
+                .layer(SetRequestIdLayer::new(
+                    HeaderName::from_static(REQUEST_ID_HEADER),
+                    MakeRequestUuid,
+                ))
+                .layer(
+                    TraceLayer::new_for_http()
+                        .make_span_with(make_request_span)
+                        .on_response(
+                            DefaultOnResponse::new()
+                                .level(Level::INFO)
+                                .latency_unit(LatencyUnit::Millis),
+                        ),
+                )
+                .layer(PropagateRequestIdLayer::new(HeaderName::from_static(
+                    REQUEST_ID_HEADER,
+                ))),
+        )
+        .with_state(state.clone());
+
+    // Admin endpoints change process-wide state and are not authenticated, so
+    // they are only served on their own listener (loopback by default), never
+    // on the port that serves traffic
+    let admin_app = Router::new()
+        .route("/admin/loglevel", get(get_log_level))
+        .route("/admin/loglevel", put(set_log_level))
+        .route_layer(middleware::from_fn(track_route_metrics))
+        .layer(TraceLayer::new_for_http().make_span_with(make_request_span))
         .with_state(state);
 
     let addr = format!("{}:{}", config.server_host, config.server_port);
     let listener = tokio::net::TcpListener::bind(&addr).await?;
+    let admin_addr = format!("{}:{}", config.admin_host, config.admin_port);
+    let admin_listener = tokio::net::TcpListener::bind(&admin_addr).await?;
 
     tracing::info!("Server listening on {}", addr);
+    tracing::info!("Admin listening on {}", admin_addr);
 
-    axum::serve(listener, app).await?;
+    // On shutdown: stop accepting connections, then wait for in-flight requests
+    let (admin_stop_tx, admin_stop_rx) = tokio::sync::oneshot::channel::<()>();
+    let serve = async {
+        axum::serve(listener, app)
+            .with_graceful_shutdown(async move {
+                shutdown.await;
+                let _ = admin_stop_tx.send(());
+            })
+            .await
+    };
+    let serve_admin = async {
+        axum::serve(admin_listener, admin_app)
+            .with_graceful_shutdown(async move {
+                let _ = admin_stop_rx.await;
+            })
+            .await
+    };
+    tokio::try_join!(serve, serve_admin)?;
+
+    tracing::info!("Server stopped");
 
     Ok(())
 }
 
+fn make_request_span(request: &Request) -> tracing::Span {
+    let request_id = request
+        .headers()
+        .get(REQUEST_ID_HEADER)
+        .and_then(|v| v.to_str().ok())
+        .unwrap_or("-");
+
+    tracing::info_span!(
+        "request",
+        method = %request.method(),
+        uri = %request.uri(),
+        request_id = %request_id,
+    )
+}
+
+/// Record latency by matched route template (e.g. `/layers/:layer_id`), so
+/// label cardinality stays bounded regardless of path parameters.
+async fn track_route_metrics(request: Request, next: Next) -> Response {
+    let route = request
+        .extensions()
+        .get::<MatchedPath>()
+        .map(|path| path.as_str().to_owned())
+        .unwrap_or_else(|| "unmatched".to_string());
+    let method = request.method().to_string();
+    let start = Instant::now();
+
+    let response = next.run(request).await;
+
+    metrics::HTTP_REQUEST_DURATION
+        .with_label_values(&[&route, &method, response.status().as_str()])
+        .observe(start.elapsed().as_secs_f64());
+
+    response
+}
+
 async fn health_check() -> impl IntoResponse {
     Json(serde_json::json!({
         "status": "healthy",
@@ -68,6 +187,61 @@ async fn health_check() -> impl IntoResponse {
     }))
 }
 
+/// Liveness: the process is up and serving HTTP
+async fn liveness() -> impl IntoResponse {
+    Json(serde_json::json!({ "status": "ok" }))
+}
+
+#[derive(serde::Serialize)]
+struct ReadinessCheck {
+    ok: bool,
+    detail: String,
+}
+
+/// Readiness: config is loaded and every component is up (or degraded but serving)
+///
+/// Counts are reported for diagnosis only: an empty catalog or a deployment
+/// with no layers yet is valid and must not hold the pod unready.
+async fn readiness(State(state): State<AppState>) -> impl IntoResponse {
+    let experiments = state.catalog.len();
+    let layers = state.layer_manager.layer_count();
+    let layers_status = state.health.snapshot().get(LAYERS).map(|c| c.status);
+
+    let mut checks = BTreeMap::new();
+    // The catalog is immutable and loaded before the server starts (startup
+    // fails otherwise), so reaching this handler means it loaded
+    checks.insert(
+        "catalog",
+        ReadinessCheck {
+            ok: true,
+            detail: format!("{} experiments loaded", experiments),
+        },
+    );
+    checks.insert(
+        "layers",
+        ReadinessCheck {
+            ok: matches!(layers_status, Some(HealthStatus::Up | HealthStatus::Degraded)),
+            detail: format!("{} layers loaded", layers),
+        },
+    );
+
+    let ready = checks.values().all(|c| c.ok) && state.health.is_ready();
+    let status = if ready {
+        StatusCode::OK
+    } else {
+        StatusCode::SERVICE_UNAVAILABLE
+    };
+
+    (
+        status,
+        Json(serde_json::json!({
+            "status": if ready { "ready" } else { "not_ready" },
+            "checks": checks,
+            "components": state.health.snapshot(),
+        })),
+    )
+}
+
 async fn experiment_handler(
     State(state): State<AppState>,
     Json(request): Json<ExperimentRequest>,
@@ -87,13 +261,20 @@ async fn experiment_handler(
             },
         )?;
 
-    // Update active layers metric
-    let total_layers: usize = response
-        .results
-        .values()
-        .map(|r| r.matched_layers.len())
-        .sum();
-    metrics::ACTIVE_LAYERS.set(total_layers as i64);
+    Ok(Json(response))
+}
+
+/// Dry-run assignment for support/QA: same semantics as `/experiment`, plus a
+/// per-layer trace. Excluded from `experiment_requests_total` and
+/// `experiment_request_duration_seconds`; latency is still recorded per route
+/// in `experiment_http_request_duration_seconds`.
+async fn evaluate_handler(
+    State(state): State<AppState>,
+    Json(request): Json<ExperimentRequest>,
+) -> Result<Json<EvaluateResponse>, AppError> {
+    let field_types = state.field_types.read().clone();
+
+    let response = evaluate_batch(&request, &state.layer_manager, &state.catalog, &field_types)?;
 
     Ok(Json(response))
 }
@@ -117,6 +298,24 @@ async fn get_layer(
     Ok(Json(serde_json::to_value(&*layer)?))
 }
 
+async fn get_layer_capacity(
+    State(state): State<AppState>,
+    Path(layer_id): Path<String>,
+) -> Result<Json<serde_json::Value>, AppError> {
+    let layer = state
+        .layer_manager
+        .get_layer(&layer_id)
+        .ok_or_else(|| crate::error::ExperimentError::LayerNotFound(layer_id.clone()))?;
+
+    Ok(Json(serde_json::json!({
+        "layer_id": layer.layer_id,
+        "total_slots": BUCKET_SIZE,
+        "allocated_slots": layer.allocated_slots(),
+        "free_slots": layer.free_slots(),
+        "experiments": layer.slots_by_experiment(&state.catalog),
+    })))
+}
+
 async fn rollback_layer(
     State(state): State<AppState>,
     Path(layer_id): Path<String>,
@@ -129,6 +328,51 @@ async fn rollback_layer(
     })))
 }
 
+async fn validate_config(State(state): State<AppState>) -> impl IntoResponse {
+    Json(serde_json::json!({
+        "priority_conflicts": state.layer_manager.get_priority_conflicts(),
+        "prerequisite_order": state.layer_manager.get_prerequisite_problems()
+    }))
+}
+
+/// Dump the in-memory config: layers with versions, the service index and the catalog
+async fn configz(State(state): State<AppState>) -> impl IntoResponse {
+    let mut experiments_by_service: BTreeMap<String, Vec<i64>> = BTreeMap::new();
+    for exp in state.catalog.experiments() {
+        experiments_by_service
+            .entry(exp.service.clone())
+            .or_default()
+            .push(exp.eid);
+    }
+    for eids in experiments_by_service.values_mut() {
+        eids.sort_unstable();
+    }
+
+    let service_index = state.layer_manager.get_service_index();
+    let mut services = BTreeMap::new();
+    for service in service_index.keys().chain(experiments_by_service.keys()) {
+        services.entry(service.clone()).or_insert_with(|| {
+            serde_json::json!({
+                "layers": service_index.get(service).cloned().unwrap_or_default(),
+                "experiments": experiments_by_service.get(service).cloned().unwrap_or_default(),
+            })
+        });
+    }
+
+    let field_types = state.field_types.read().clone();
+
+    Json(serde_json::json!({
+        "layers": state.layer_manager.get_layer_statuses(),
+        "services": services,
+        "catalog": {
+            "source_dir": state.catalog.source_dir(),
+            "experiments": state.catalog.len(),
+            "segments": state.catalog.segments().names(),
+        },
+        "field_types": field_types,
+    }))
+}
+
 async fn get_field_types(State(state): State<AppState>) -> impl IntoResponse {
     let field_types = state.field_types.read().clone();
     Json(field_types)
@@ -137,19 +381,47 @@ async fn get_field_types(State(state): State<AppState>) -> impl IntoResponse {
 async fn update_field_types(
     State(state): State<AppState>,
     Json(new_field_types): Json<HashMap<String, FieldType>>,
-) -> impl IntoResponse {
+) -> Result<impl IntoResponse, AppError> {
+    // Every loaded rule must type-check against the new map before it is swapped in
+    let mut problems = Vec::new();
+    let mut experiments: Vec<_> = state.catalog.experiments().collect();
+    experiments.sort_by_key(|exp| exp.eid);
+    for exp in experiments {
+        if let Some(Err(e)) = exp.rule.as_ref().map(|rule| rule.validate(&new_field_types)) {
+            problems.push(format!("experiment {}: {}", exp.eid, e));
+        }
+    }
+    for (name, rule) in state.catalog.segments().rules() {
+        if let Err(e) = rule.validate(&new_field_types) {
+            problems.push(format!("segment '{}': {}", name, e));
+        }
+    }
+    if !problems.is_empty() {
+        return Err(ExperimentError::InvalidRule(format!(
+            "field types rejected, {} rule(s) do not validate: {}",
+            problems.len(),
+            problems.join("; ")
+        ))
+        .into());
+    }
+
     let mut field_types = state.field_types.write();
     *field_types = new_field_types;
 
     tracing::info!("Updated field types: {} fields", field_types.len());
 
-    Json(serde_json::json!({
+    Ok(Json(serde_json::json!({
         "status": "success",
         "message": format!("Updated {} field types", field_types.len())
-    }))
+    })))
 }
 
-async fn metrics_handler() -> impl IntoResponse {
+async fn metrics_handler(State(state): State<AppState>) -> impl IntoResponse {
+    // Entity counts are read at scrape time so they always match loaded state
+    metrics::ACTIVE_LAYERS.set(state.layer_manager.enabled_layer_count() as i64);
+    metrics::LOADED_LAYERS.set(state.layer_manager.layer_count() as i64);
+    metrics::CATALOG_EXPERIMENTS.set(state.catalog.len() as i64);
+
     let encoder = TextEncoder::new();
     let metric_families = metrics::REGISTRY.gather();
     let mut buffer = vec![];
@@ -162,18 +434,59 @@ async fn metrics_handler() -> impl IntoResponse {
     )
 }
 
+#[derive(serde::Deserialize)]
+struct LogLevelRequest {
+    /// EnvFilter directives, same syntax as RUST_LOG (e.g. "experiment_data_plane=debug")
+    filter: String,
+}
+
+async fn get_log_level(State(state): State<AppState>) -> Result<impl IntoResponse, AppError> {
+    let filter = state.log_filter.with_current(|f| f.to_string())?;
+    Ok(Json(serde_json::json!({ "filter": filter })))
+}
+
+async fn set_log_level(
+    State(state): State<AppState>,
+    Json(request): Json<LogLevelRequest>,
+) -> Result<impl IntoResponse, AppError> {
+    let filter = EnvFilter::try_new(&request.filter).map_err(|e| {
+        ExperimentError::InvalidParameter(format!("Invalid log filter '{}': {}", request.filter, e))
+    })?;
+    state.log_filter.reload(filter)?;
+
+    tracing::warn!("Log filter changed to '{}'", request.filter);
+
+    Ok(Json(serde_json::json!({ "filter": request.filter })))
+}
+
 // Error handling
 struct AppError(anyhow::Error);
 
 impl IntoResponse for AppError {
     fn into_response(self) -> Response {
+        let kind = self
+            .0
+            .downcast_ref::<ExperimentError>()
+            .map_or(ErrorKind::Internal, ExperimentError::kind);
+        let status = match kind {
+            ErrorKind::NotFound => StatusCode::NOT_FOUND,
+            ErrorKind::Conflict => StatusCode::CONFLICT,
+            ErrorKind::Validation => StatusCode::BAD_REQUEST,
+            ErrorKind::Internal => StatusCode::INTERNAL_SERVER_ERROR,
+        };
+
         let message = self.0.to_string();
-        tracing::error!("Request error: {}", message);
+        if status.is_server_error() {
+            tracing::error!("Request error: {}", message);
+        } else {
+            tracing::warn!("Request rejected ({}): {}", status, message);
+        }
 
         (
-            StatusCode::INTERNAL_SERVER_ERROR,
+            status,
             Json(serde_json::json!({
-                "error": message
+                "error": message,
+                "code": kind.code(),
             })),
         )
             .into_response()
@@ -1,13 +1,27 @@
 use crate::catalog::ExperimentCatalog;
+use crate::error::ExperimentError;
+use crate::health::{HealthRegistry, LAYERS, WATCHER};
 use crate::layer::LayerManager;
 use anyhow::Result;
 use notify::{Config, Event, EventKind, RecommendedWatcher, RecursiveMode, Watcher};
 use std::path::Path;
 use std::sync::Arc;
-use tokio::sync::mpsc;
+use tokio::sync::{mpsc, oneshot};
 
 /// Watch layers directory for changes and hot reload
-pub async fn watch_layers(manager: Arc<LayerManager>, catalog: Arc<ExperimentCatalog>) -> Result<()> {
+///
+/// Reports the `watcher` component as up while the directory is watched and
+/// down once the event loop exits. Reload and removal failures are tracked per
+/// layer on `layers`, and cleared when that layer next reloads or is removed.
+///
+/// Returns once `stop` fires. A reload that is already in progress is finished
+/// first, so shutdown never leaves a layer half-applied.
+pub async fn watch_layers(
+    manager: Arc<LayerManager>,
+    catalog: Arc<ExperimentCatalog>,
+    health: Arc<HealthRegistry>,
+    mut stop: oneshot::Receiver<()>,
+) -> Result<()> {
     let (tx, mut rx) = mpsc::channel(100);
     
     let layers_dir = manager.layers_dir.clone();
@@ -26,20 +40,32 @@ pub async fn watch_layers(manager: Arc<LayerManager>, catalog: Arc<ExperimentCat
     watcher.watch(&layers_dir, RecursiveMode::NonRecursive)?;
     
     tracing::info!("Watching layers directory: {:?}", layers_dir);
+    health.set_up(WATCHER);
     
     // Process events
-    while let Some(event) = rx.recv().await {
+    loop {
+        let event = tokio::select! {
+            event = rx.recv() => match event {
+                Some(event) => event,
+                None => break,
+            },
+            _ = &mut stop => {
+                tracing::info!("Stopping layers watcher");
+                break;
+            }
+        };
+
         match event.kind {
             EventKind::Create(_) | EventKind::Modify(_) => {
                 for path in event.paths {
-                    if let Err(e) = handle_file_change(&manager, &catalog, &path).await {
+                    if let Err(e) = handle_file_change(&manager, &catalog, &health, &path).await {
                         tracing::error!("Failed to handle file change {:?}: {}", path, e);
                     }
                 }
             }
             EventKind::Remove(_) => {
                 for path in event.paths {
-                    if let Err(e) = handle_file_remove(&manager, &catalog, &path).await {
+                    if let Err(e) = handle_file_remove(&manager, &catalog, &health, &path).await {
                         tracing::error!("Failed to handle file remove {:?}: {}", path, e);
                     }
                 }
@@ -48,10 +74,16 @@ pub async fn watch_layers(manager: Arc<LayerManager>, catalog: Arc<ExperimentCat
         }
     }
     
+    health.set_down(WATCHER, None);
     Ok(())
 }
 
-async fn handle_file_change(manager: &LayerManager, catalog: &ExperimentCatalog, path: &Path) -> Result<()> {
+async fn handle_file_change(
+    manager: &LayerManager,
+    catalog: &ExperimentCatalog,
+    health: &HealthRegistry,
+    path: &Path,
+) -> Result<()> {
     if !path.is_file() {
         return Ok(());
     }
@@ -72,10 +104,16 @@ async fn handle_file_change(manager: &LayerManager, catalog: &ExperimentCatalog,
                     Ok(_) => {
                         tracing::info!("Hot reloaded layer: {}", layer_id);
                         crate::metrics::LAYER_RELOAD_TOTAL.inc();
+                        health.clear_failure(LAYERS, &layer_id);
                     }
                     Err(e) => {
                         tracing::error!("Failed to reload layer {}: {}", layer_id, e);
                         crate::metrics::LAYER_RELOAD_ERRORS.inc();
+                        health.record_failure(
+                            LAYERS,
+                            &layer_id,
+                            format!("Failed to reload layer {}: {}", layer_id, e),
+                        );
                     }
                 }
             }
@@ -85,16 +123,35 @@ async fn handle_file_change(manager: &LayerManager, catalog: &ExperimentCatalog,
     Ok(())
 }
 
-async fn handle_file_remove(manager: &LayerManager, catalog: &ExperimentCatalog, path: &Path) -> Result<()> {
+async fn handle_file_remove(
+    manager: &LayerManager,
+    catalog: &ExperimentCatalog,
+    health: &HealthRegistry,
+    path: &Path,
+) -> Result<()> {
     if let Some(file_stem) = path.file_stem() {
         let layer_id = file_stem.to_string_lossy();
         
         tracing::info!("Detected removal of layer file: {:?}", path);
         
-        if let Err(e) = manager.remove_layer(&layer_id, catalog).await {
-            tracing::error!("Failed to remove layer {}: {}", layer_id, e);
-        } else {
-            tracing::info!("Removed layer: {}", layer_id);
+        match manager.remove_layer(&layer_id, catalog).await {
+            Ok(()) => {
+                tracing::info!("Removed layer: {}", layer_id);
+                health.clear_failure(LAYERS, &layer_id);
+            }
+            // Never loaded (e.g. its last reload failed): with the file gone
+            // there is nothing left failing for this layer
+            Err(ExperimentError::LayerNotFound(_)) => {
+                health.clear_failure(LAYERS, &layer_id);
+            }
+            Err(e) => {
+                tracing::error!("Failed to remove layer {}: {}", layer_id, e);
+                health.record_failure(
+                    LAYERS,
+                    &layer_id,
+                    format!("Failed to remove layer {}: {}", layer_id, e),
+                );
+            }
         }
     }
     
@@ -1,7 +1,7 @@
-use experiment_data_plane::catalog::{ExperimentCatalog, ExperimentDef, VariantDef};
+use experiment_data_plane::catalog::{ExperimentCatalog, ExperimentDef, Prerequisite, VariantDef};
 use experiment_data_plane::hash::hash_to_bucket;
 use experiment_data_plane::layer::{BucketRange, Layer, LayerManager, BUCKET_SIZE};
-use experiment_data_plane::merge::{merge_layers_batch, ExperimentRequest};
+use experiment_data_plane::merge::{evaluate_batch, merge_layers_batch, ExperimentRequest};
 use serde_json::json;
 use std::collections::HashMap;
 use std::sync::Arc;
@@ -30,6 +30,8 @@ async fn test_layer_loading_and_service_index() {
                 params: json!({"feature": "b"}),
             },
         ],
+        overrides: HashMap::new(),
+        prerequisites: vec![],
     };
     std::fs::write(
         experiments_dir.join("100.json"),
@@ -101,6 +103,8 @@ async fn test_merge_with_ranges() {
                 params: json!({"timeout": 200, "cache": true}),
             },
         ],
+        overrides: HashMap::new(),
+        prerequisites: vec![],
     };
     std::fs::write(
         experiments_dir.join("200.json"),
@@ -172,6 +176,7 @@ async fn test_eid_rule_evaluation_memo() {
             field: "region".to_string(),
             op: experiment_data_plane::rule::Op::Eq,
             values: vec![json!("US")],
+            regex: Default::default(),
         }),
         variants: vec![
             VariantDef {
@@ -183,6 +188,8 @@ async fn test_eid_rule_evaluation_memo() {
                 params: json!({"feature": "b"}),
             },
         ],
+        overrides: HashMap::new(),
+        prerequisites: vec![],
     };
     std::fs::write(
         experiments_dir.join("300.json"),
@@ -265,3 +272,229 @@ async fn test_eid_rule_evaluation_memo() {
     assert!(result.vids.contains(&3001));
     assert!(result.vids.contains(&3002));
 }
+
+#[tokio::test]
+async fn test_override_forces_variant() {
+    let temp_dir = TempDir::new().unwrap();
+    let layers_dir = temp_dir.path().join("layers");
+    let experiments_dir = temp_dir.path().join("experiments");
+    std::fs::create_dir_all(&layers_dir).unwrap();
+    std::fs::create_dir_all(&experiments_dir).unwrap();
+
+    let test_user = "user_qa";
+    let salt = "override_salt";
+    let bucket = hash_to_bucket(test_user, salt);
+
+    // Bucketing alone puts the user in 5001; the override pins them to 5002,
+    // and the rule (which the request does not satisfy) is bypassed
+    let exp = ExperimentDef {
+        eid: 500,
+        service: "api".to_string(),
+        rule: Some(experiment_data_plane::rule::Node::Field {
+            field: "region".to_string(),
+            op: experiment_data_plane::rule::Op::Eq,
+            values: vec![json!("US")],
+            regex: Default::default(),
+        }),
+        variants: vec![
+            VariantDef {
+                vid: 5001,
+                params: json!({"feature": "control"}),
+            },
+            VariantDef {
+                vid: 5002,
+                params: json!({"feature": "treatment"}),
+            },
+        ],
+        overrides: [(test_user.to_string(), 5002)].into_iter().collect(),
+        prerequisites: vec![],
+    };
+    std::fs::write(
+        experiments_dir.join("500.json"),
+        serde_json::to_string_pretty(&exp).unwrap(),
+    )
+    .unwrap();
+
+    let catalog = Arc::new(ExperimentCatalog::load_from_dir(experiments_dir).unwrap());
+
+    let layer = Layer {
+        layer_id: "override_layer".to_string(),
+        version: "v1".to_string(),
+        priority: 100,
+        hash_key: "user_id".to_string(),
+        salt: Some(salt.to_string()),
+        services: vec![],
+        ranges: vec![
+            BucketRange {
+                start: bucket,
+                end: bucket.saturating_add(1).min(BUCKET_SIZE),
+                vid: 5001,
+            },
+            BucketRange {
+                start: BUCKET_SIZE - 1,
+                end: BUCKET_SIZE,
+                vid: 5002,
+            },
+        ],
+        enabled: true,
+    };
+    std::fs::write(
+        layers_dir.join("override_layer.json"),
+        serde_json::to_string_pretty(&layer).unwrap(),
+    )
+    .unwrap();
+
+    let manager = LayerManager::new(layers_dir);
+    manager.load_all_layers(&catalog).await.unwrap();
+
+    let mut field_types = HashMap::new();
+    field_types.insert("region".to_string(), experiment_data_plane::rule::FieldType::String);
+
+    let request = ExperimentRequest {
+        services: vec!["api".to_string()],
+        context: [
+            ("user_id".to_string(), json!(test_user)),
+            ("region".to_string(), json!("CN")),
+        ]
+        .into_iter()
+        .collect(),
+        layers: vec![],
+    };
+    let response = merge_layers_batch(&request, &manager, &catalog, &field_types).unwrap();
+    let result = response.results.get("api").unwrap();
+    assert_eq!(result.vids, vec![5002]);
+    assert_eq!(result.parameters["feature"], json!("treatment"));
+
+    // Other units still go through bucketing and the rule
+    let request = ExperimentRequest {
+        services: vec!["api".to_string()],
+        context: [
+            ("user_id".to_string(), json!("someone_else")),
+            ("region".to_string(), json!("CN")),
+        ]
+        .into_iter()
+        .collect(),
+        layers: vec![],
+    };
+    let response = merge_layers_batch(&request, &manager, &catalog, &field_types).unwrap();
+    assert!(response.results.get("api").unwrap().vids.is_empty());
+}
+
+#[test]
+fn test_override_outside_experiment_rejected() {
+    let temp_dir = TempDir::new().unwrap();
+
+    let exp = ExperimentDef {
+        eid: 600,
+        service: "api".to_string(),
+        rule: None,
+        variants: vec![VariantDef {
+            vid: 6001,
+            params: json!({}),
+        }],
+        overrides: [("user_1".to_string(), 9999)].into_iter().collect(),
+        prerequisites: vec![],
+    };
+    std::fs::write(
+        temp_dir.path().join("600.json"),
+        serde_json::to_string_pretty(&exp).unwrap(),
+    )
+    .unwrap();
+
+    let err = ExperimentCatalog::load_from_dir(temp_dir.path().to_path_buf()).unwrap_err();
+    assert!(err.to_string().contains("outside the experiment"));
+}
+
+#[tokio::test]
+async fn test_prerequisite_resolved_by_higher_priority_layer() {
+    let temp_dir = TempDir::new().unwrap();
+    let layers_dir = temp_dir.path().join("layers");
+    let experiments_dir = temp_dir.path().join("experiments");
+    std::fs::create_dir_all(&layers_dir).unwrap();
+    std::fs::create_dir_all(&experiments_dir).unwrap();
+
+    // 900 only applies to units in variant 8001 of 800
+    let base = ExperimentDef {
+        eid: 800,
+        service: "api".to_string(),
+        rule: None,
+        variants: vec![VariantDef {
+            vid: 8001,
+            params: json!({"base": true}),
+        }],
+        overrides: HashMap::new(),
+        prerequisites: vec![],
+    };
+    let gated = ExperimentDef {
+        eid: 900,
+        service: "api".to_string(),
+        rule: None,
+        variants: vec![VariantDef {
+            vid: 9001,
+            params: json!({"gated": true}),
+        }],
+        overrides: HashMap::new(),
+        prerequisites: vec![Prerequisite {
+            eid: 800,
+            vids: vec![8001],
+        }],
+    };
+    for exp in [&base, &gated] {
+        std::fs::write(
+            experiments_dir.join(format!("{}.json", exp.eid)),
+            serde_json::to_string_pretty(exp).unwrap(),
+        )
+        .unwrap();
+    }
+
+    let catalog = Arc::new(ExperimentCatalog::load_from_dir(experiments_dir).unwrap());
+
+    let layer = |layer_id: &str, priority: i32, vid: i64| Layer {
+        layer_id: layer_id.to_string(),
+        version: "v1".to_string(),
+        priority,
+        hash_key: "user_id".to_string(),
+        salt: None,
+        services: vec![],
+        ranges: vec![BucketRange {
+            start: 0,
+            end: BUCKET_SIZE,
+            vid,
+        }],
+        enabled: true,
+    };
+    for layer in [layer("base_layer", 200, 8001), layer("gated_layer", 100, 9001)] {
+        std::fs::write(
+            layers_dir.join(format!("{}.json", layer.layer_id)),
+            serde_json::to_string_pretty(&layer).unwrap(),
+        )
+        .unwrap();
+    }
+
+    let manager = LayerManager::new(layers_dir);
+    manager.load_all_layers(&catalog).await.unwrap();
+
+    let field_types = HashMap::new();
+    let request = |layers: Vec<String>| ExperimentRequest {
+        services: vec!["api".to_string()],
+        context: [("user_id".to_string(), json!("user_1"))].into_iter().collect(),
+        layers,
+    };
+
+    // Base layer resolves first, so the prerequisite is met
+    let response = merge_layers_batch(&request(vec![]), &manager, &catalog, &field_types).unwrap();
+    assert_eq!(response.results.get("api").unwrap().vids, vec![8001, 9001]);
+
+    // Without the base layer the gated experiment is skipped
+    let response = evaluate_batch(
+        &request(vec!["gated_layer".to_string()]),
+        &manager,
+        &catalog,
+        &field_types,
+    )
+    .unwrap();
+    let eval = response.results.get("api").unwrap();
+    assert!(eval.result.vids.is_empty());
+    assert_eq!(eval.layers[0].outcome, "prerequisite_unmet");
+    assert_eq!(eval.layers[0].unmet_prerequisite, Some(800));
+}
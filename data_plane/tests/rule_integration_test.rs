@@ -1,8 +1,9 @@
 use experiment_data_plane::catalog::{ExperimentCatalog, ExperimentDef, VariantDef};
 use experiment_data_plane::hash::hash_to_bucket;
 use experiment_data_plane::layer::{BucketRange, Layer, LayerManager, BUCKET_SIZE};
-use experiment_data_plane::merge::{merge_layers_batch, ExperimentRequest};
+use experiment_data_plane::merge::{evaluate_batch, merge_layers_batch, ExperimentRequest};
 use experiment_data_plane::rule::{FieldType, Node, Op};
+use experiment_data_plane::segment::{SegmentDef, SegmentSet};
 use serde_json::json;
 use std::collections::HashMap;
 use std::sync::Arc;
@@ -23,11 +24,14 @@ async fn test_rule_based_experiment() {
             field: "country".to_string(),
             op: Op::Eq,
             values: vec![json!("CN")],
+            regex: Default::default(),
         }),
         variants: vec![VariantDef {
             vid: 4001,
             params: json!({"feature": "china_special"}),
         }],
+        overrides: HashMap::new(),
+        prerequisites: vec![],
     };
 
     std::fs::write(
@@ -110,3 +114,244 @@ async fn test_rule_based_experiment() {
         assert_eq!(result.vids.len(), 0);
     }
 }
+
+#[tokio::test]
+async fn test_evaluate_reports_layer_outcomes() {
+    let temp_dir = TempDir::new().unwrap();
+    let layers_dir = temp_dir.path().join("layers");
+    let experiments_dir = temp_dir.path().join("experiments");
+    std::fs::create_dir_all(&layers_dir).unwrap();
+    std::fs::create_dir_all(&experiments_dir).unwrap();
+
+    let exp = ExperimentDef {
+        eid: 700,
+        service: "api".to_string(),
+        rule: Some(Node::Field {
+            field: "country".to_string(),
+            op: Op::Eq,
+            values: vec![json!("CN")],
+            regex: Default::default(),
+        }),
+        variants: vec![
+            VariantDef {
+                vid: 7001,
+                params: json!({"feature": "a"}),
+            },
+            VariantDef {
+                vid: 7002,
+                params: json!({"feature": "b"}),
+            },
+        ],
+        overrides: HashMap::new(),
+        prerequisites: vec![],
+    };
+    std::fs::write(
+        experiments_dir.join("700.json"),
+        serde_json::to_string_pretty(&exp).unwrap(),
+    )
+    .unwrap();
+
+    let catalog = Arc::new(ExperimentCatalog::load_from_dir(experiments_dir).unwrap());
+
+    let test_user = "user_eval";
+    let hit_salt = "hit_salt";
+    let hole_salt = "hole_salt";
+    let hit_bucket = hash_to_bucket(test_user, hit_salt);
+    let hole_bucket = hash_to_bucket(test_user, hole_salt);
+
+    // Covers the user's bucket
+    let hit_layer = Layer {
+        layer_id: "hit_layer".to_string(),
+        version: "v1".to_string(),
+        priority: 200,
+        hash_key: "user_id".to_string(),
+        salt: Some(hit_salt.to_string()),
+        services: vec![],
+        ranges: vec![BucketRange {
+            start: hit_bucket,
+            end: hit_bucket + 1,
+            vid: 7001,
+        }],
+        enabled: true,
+    };
+
+    // Covers every bucket except the user's
+    let mut hole_ranges = Vec::new();
+    if hole_bucket > 0 {
+        hole_ranges.push(BucketRange {
+            start: 0,
+            end: hole_bucket,
+            vid: 7002,
+        });
+    }
+    if hole_bucket + 1 < BUCKET_SIZE {
+        hole_ranges.push(BucketRange {
+            start: hole_bucket + 1,
+            end: BUCKET_SIZE,
+            vid: 7002,
+        });
+    }
+    let hole_layer = Layer {
+        layer_id: "hole_layer".to_string(),
+        version: "v1".to_string(),
+        priority: 100,
+        hash_key: "user_id".to_string(),
+        salt: Some(hole_salt.to_string()),
+        services: vec![],
+        ranges: hole_ranges,
+        enabled: true,
+    };
+
+    for layer in [&hit_layer, &hole_layer] {
+        std::fs::write(
+            layers_dir.join(format!("{}.json", layer.layer_id)),
+            serde_json::to_string_pretty(layer).unwrap(),
+        )
+        .unwrap();
+    }
+
+    let manager = LayerManager::new(layers_dir);
+    manager.load_all_layers(&catalog).await.unwrap();
+
+    let mut field_types = HashMap::new();
+    field_types.insert("country".to_string(), FieldType::String);
+
+    let request = |country: &str| ExperimentRequest {
+        services: vec!["api".to_string()],
+        context: [
+            ("user_id".to_string(), json!(test_user)),
+            ("country".to_string(), json!(country)),
+        ]
+        .into_iter()
+        .collect(),
+        layers: vec![],
+    };
+
+    // Rule passes: hit layer matches, hole layer is unassigned
+    let response = evaluate_batch(&request("CN"), &manager, &catalog, &field_types).unwrap();
+    let eval = response.results.get("api").unwrap();
+    assert_eq!(eval.result.vids, vec![7001]);
+    assert_eq!(eval.layers.len(), 2);
+    assert_eq!(eval.layers[0].layer_id, "hit_layer");
+    assert_eq!(eval.layers[0].outcome, "matched");
+    assert_eq!(eval.layers[0].bucket, Some(hit_bucket));
+    assert_eq!(eval.layers[0].eid, Some(700));
+    assert_eq!(eval.layers[0].rule_branches, vec!["field(country eq)"]);
+    assert_eq!(eval.layers[1].layer_id, "hole_layer");
+    assert_eq!(eval.layers[1].outcome, "unassigned");
+    assert_eq!(eval.layers[1].bucket, Some(hole_bucket));
+    assert_eq!(eval.layers[1].vid, None);
+
+    // Rule fails: same bucket and vid, but rejected
+    let response = evaluate_batch(&request("US"), &manager, &catalog, &field_types).unwrap();
+    let eval = response.results.get("api").unwrap();
+    assert!(eval.result.vids.is_empty());
+    assert_eq!(eval.layers[0].outcome, "rule_rejected");
+    assert_eq!(eval.layers[0].vid, Some(7001));
+    assert_eq!(eval.layers[0].rule_error, None);
+    assert_eq!(eval.layers[0].rule_branches, vec!["field(country eq)"]);
+    assert!(eval.layers[1].rule_branches.is_empty());
+
+    // Dry-run agrees with the regular merge
+    let merged = merge_layers_batch(&request("CN"), &manager, &catalog, &field_types).unwrap();
+    assert_eq!(merged.results.get("api").unwrap().vids, vec![7001]);
+}
+
+#[tokio::test]
+async fn test_in_segment_experiment() {
+    let temp_dir = TempDir::new().unwrap();
+    let layers_dir = temp_dir.path().join("layers");
+    let experiments_dir = temp_dir.path().join("experiments");
+    let segments_dir = temp_dir.path().join("segments");
+    std::fs::create_dir_all(&layers_dir).unwrap();
+    std::fs::create_dir_all(&experiments_dir).unwrap();
+    std::fs::create_dir_all(&segments_dir).unwrap();
+
+    let exp = ExperimentDef {
+        eid: 800,
+        service: "api".to_string(),
+        rule: Some(Node::Field {
+            field: "user_id".to_string(),
+            op: Op::InSegment,
+            values: vec![json!("beta_testers")],
+            regex: Default::default(),
+        }),
+        variants: vec![VariantDef {
+            vid: 8001,
+            params: json!({"feature": "beta"}),
+        }],
+        overrides: HashMap::new(),
+        prerequisites: vec![],
+    };
+    std::fs::write(
+        experiments_dir.join("800.json"),
+        serde_json::to_string_pretty(&exp).unwrap(),
+    )
+    .unwrap();
+
+    // Experiment references a segment that is not defined yet
+    let segments = SegmentSet::load_from_dir(segments_dir.clone()).unwrap();
+    let err = ExperimentCatalog::load_from_dir(experiments_dir.clone())
+        .unwrap()
+        .with_segments(segments)
+        .unwrap_err();
+    assert!(err.to_string().contains("unknown segment 'beta_testers'"));
+
+    let segment = SegmentDef {
+        name: "beta_testers".to_string(),
+        rule: None,
+        ids: vec!["user_beta".to_string()],
+    };
+    std::fs::write(
+        segments_dir.join("beta_testers.json"),
+        serde_json::to_string_pretty(&segment).unwrap(),
+    )
+    .unwrap();
+
+    let segments = SegmentSet::load_from_dir(segments_dir).unwrap();
+    let catalog = Arc::new(
+        ExperimentCatalog::load_from_dir(experiments_dir)
+            .unwrap()
+            .with_segments(segments)
+            .unwrap(),
+    );
+
+    // Layer covers every bucket so only the rule decides
+    let layer = Layer {
+        layer_id: "beta_layer".to_string(),
+        version: "v1".to_string(),
+        priority: 100,
+        hash_key: "user_id".to_string(),
+        salt: None,
+        services: vec![],
+        ranges: vec![BucketRange {
+            start: 0,
+            end: BUCKET_SIZE,
+            vid: 8001,
+        }],
+        enabled: true,
+    };
+    std::fs::write(
+        layers_dir.join("beta_layer.json"),
+        serde_json::to_string_pretty(&layer).unwrap(),
+    )
+    .unwrap();
+
+    let manager = LayerManager::new(layers_dir);
+    manager.load_all_layers(&catalog).await.unwrap();
+
+    let mut field_types = HashMap::new();
+    field_types.insert("user_id".to_string(), FieldType::String);
+
+    let request = |user_id: &str| ExperimentRequest {
+        services: vec!["api".to_string()],
+        context: [("user_id".to_string(), json!(user_id))].into_iter().collect(),
+        layers: vec![],
+    };
+
+    let response = merge_layers_batch(&request("user_beta"), &manager, &catalog, &field_types).unwrap();
+    assert_eq!(response.results.get("api").unwrap().vids, vec![8001]);
+
+    let response = merge_layers_batch(&request("user_other"), &manager, &catalog, &field_types).unwrap();
+    assert!(response.results.get("api").unwrap().vids.is_empty());
+}
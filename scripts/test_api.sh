@@ -11,6 +11,8 @@ echo "================================================"
 echo -e "\n1. Health Check"
 echo "GET /health"
 curl -s "$BASE_URL/health" | jq .
+echo "GET /readyz"
+curl -s "$BASE_URL/readyz" | jq .
 
 # List layers
 echo -e "\n2. List Layers"